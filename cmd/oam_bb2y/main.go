@@ -0,0 +1,128 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+// oam_bb2y: Converts bug bounty program scope exports to YAML!
+//
+//	+----------------------------------------------------------------------------+
+//	| ░░░░░░░░░░░░░░░░░░░░░░░░░░░░░  OWASP Amass  ░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░ |
+//	+----------------------------------------------------------------------------+
+//	|      .+++:.            :                             .+++.                 |
+//	|    +W@@@@@@8        &+W@#               o8W8:      +W@@@@@@#.   oW@@@W#+   |
+//	|   &@#+   .o@##.    .@@@o@W.o@@o       :@@#&W8o    .@#:  .:oW+  .@#+++&#&   |
+//	|  +@&        &@&     #@8 +@W@&8@+     :@W.   +@8   +@:          .@8         |
+//	|  8@          @@     8@o  8@8  WW    .@W      W@+  .@W.          o@#:       |
+//	|  WW          &@o    &@:  o@+  o@+   #@.      8@o   +W@#+.        +W@8:     |
+//	|  #@          :@W    &@+  &@+   @8  :@o       o@o     oW@@W+        oW@8    |
+//	|  o@+          @@&   &@+  &@+   #@  &@.      .W@W       .+#@&         o@W.  |
+//	|   WW         +@W@8. &@+  :&    o@+ #@      :@W&@&         &@:  ..     :@o  |
+//	|   :@W:      o@# +Wo &@+        :W: +@W&o++o@W. &@&  8@#o+&@W.  #@:    o@+  |
+//	|    :W@@WWWW@@8       +              :&W@@@@&    &W  .o#@@W&.   :W@WWW@@&   |
+//	|      +o&&&&+.                                                    +oooo.    |
+//	+----------------------------------------------------------------------------+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/owasp-amass/config/config"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	usageMsg = "-platform hackerone|bugcrowd -export path [options]"
+)
+
+var (
+	g = color.New(color.FgHiGreen)
+	r = color.New(color.FgHiRed)
+	y = color.New(color.FgHiYellow)
+	b = color.New(color.FgHiBlue)
+	p = color.New(color.FgHiMagenta)
+)
+
+func main() {
+	var help1, help2 bool
+	var platform, exportFile, configFile string
+	var err error
+	bb2yCommand := flag.NewFlagSet("bb2y", flag.ContinueOnError)
+
+	bb2yBuf := new(bytes.Buffer)
+	bb2yCommand.SetOutput(bb2yBuf)
+
+	bb2yCommand.BoolVar(&help1, "h", false, "Show the program usage message")
+	bb2yCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	bb2yCommand.StringVar(&platform, "platform", "", "Bug bounty platform of the export (hackerone or bugcrowd).")
+	bb2yCommand.StringVar(&exportFile, "export", "", "Path to the program scope export file (CSV or JSON).")
+	bb2yCommand.StringVar(&configFile, "cf", "oam_config.yaml", "YAML configuration file name.")
+
+	var usage = func() {
+		g.Fprintf(color.Error, "Usage: %s %s\n\n", path.Base(os.Args[0]), usageMsg)
+		bb2yCommand.PrintDefaults()
+		g.Fprintln(color.Error, bb2yBuf.String())
+	}
+
+	if len(os.Args) < 2 {
+		usage()
+		return
+	}
+	if err := bb2yCommand.Parse(os.Args[1:]); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if help1 || help2 {
+		usage()
+		return
+	}
+	if exportFile == "" {
+		usage()
+		r.Fprintln(color.Error, "Failed to parse the scope export: File not present, got \""+exportFile+"\" as the path.")
+		return
+	}
+
+	var importer func(string) (*config.Scope, []string, error)
+	switch strings.ToLower(platform) {
+	case "hackerone", "h1":
+		importer = config.ImportHackerOneScope
+	case "bugcrowd", "bc":
+		importer = config.ImportBugcrowdScope
+	default:
+		usage()
+		r.Fprintln(color.Error, "Failed to import the scope: Unsupported platform, got \""+platform+"\".")
+		return
+	}
+
+	// converts the file path to an absolute path
+	configFile, err = filepath.Abs(configFile)
+	if err != nil {
+		log.Fatal("Failed to get the absolute config path:", err)
+	}
+
+	scope, skipped, err := importer(exportFile)
+	if err != nil {
+		log.Fatal("Failed to import the scope export: ", err)
+	}
+	for _, target := range skipped {
+		fmt.Fprintln(color.Error, y.Sprint("Skipped the target that cannot be mapped into the scope: ")+target)
+	}
+
+	// marshals only the scope section and outputs it into a file
+	output, err := yaml.Marshal(&struct {
+		Scope *config.Scope `yaml:"scope"`
+	}{Scope: scope})
+	if err != nil {
+		log.Fatal("failed to marshal the yaml:", err)
+	}
+	if err := os.WriteFile(configFile, output, 0644); err != nil {
+		log.Fatal("Failed to write config file:", err)
+	}
+	fmt.Println(b.Sprint("Wrote config file successfully at ") + p.Sprint(configFile))
+}
@@ -0,0 +1,128 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+// oam_explain: Explains why names and addresses are in or out of scope!
+//
+//	+----------------------------------------------------------------------------+
+//	| ░░░░░░░░░░░░░░░░░░░░░░░░░░░░░  OWASP Amass  ░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░ |
+//	+----------------------------------------------------------------------------+
+//	|      .+++:.            :                             .+++.                 |
+//	|    +W@@@@@@8        &+W@#               o8W8:      +W@@@@@@#.   oW@@@W#+   |
+//	|   &@#+   .o@##.    .@@@o@W.o@@o       :@@#&W8o    .@#:  .:oW+  .@#+++&#&   |
+//	|  +@&        &@&     #@8 +@W@&8@+     :@W.   +@8   +@:          .@8         |
+//	|  8@          @@     8@o  8@8  WW    .@W      W@+  .@W.          o@#:       |
+//	|  WW          &@o    &@:  o@+  o@+   #@.      8@o   +W@#+.        +W@8:     |
+//	|  #@          :@W    &@+  &@+   @8  :@o       o@o     oW@@W+        oW@8    |
+//	|  o@+          @@&   &@+  &@+   #@  &@.      .W@W       .+#@&         o@W.  |
+//	|   WW         +@W@8. &@+  :&    o@+ #@      :@W&@&         &@:  ..     :@o  |
+//	|   :@W:      o@# +Wo &@+        :W: +@W&o++o@W. &@&  8@#o+&@W.  #@:    o@+  |
+//	|    :W@@WWWW@@8       +              :&W@@@@&    &W  .o#@@W&.   :W@WWW@@&   |
+//	|      +o&&&&+.                                                    +oooo.    |
+//	+----------------------------------------------------------------------------+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/owasp-amass/config/config"
+)
+
+const (
+	usageMsg = "-config path [options] < names.txt"
+)
+
+var (
+	g = color.New(color.FgHiGreen)
+	r = color.New(color.FgHiRed)
+	y = color.New(color.FgHiYellow)
+)
+
+func main() {
+	var help1, help2, jsonOutput bool
+	var configFile string
+	explainCommand := flag.NewFlagSet("explain", flag.ContinueOnError)
+
+	explainBuf := new(bytes.Buffer)
+	explainCommand.SetOutput(explainBuf)
+
+	explainCommand.BoolVar(&help1, "h", false, "Show the program usage message")
+	explainCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	explainCommand.StringVar(&configFile, "config", "", "Path to the YAML configuration file.")
+	explainCommand.BoolVar(&jsonOutput, "json", false, "Print each verdict as a line of JSON.")
+
+	var usage = func() {
+		g.Fprintf(color.Error, "Usage: %s %s\n\n", path.Base(os.Args[0]), usageMsg)
+		explainCommand.PrintDefaults()
+		g.Fprintln(color.Error, explainBuf.String())
+	}
+
+	if len(os.Args) < 2 {
+		usage()
+		return
+	}
+	if err := explainCommand.Parse(os.Args[1:]); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if help1 || help2 {
+		usage()
+		return
+	}
+	if configFile == "" {
+		usage()
+		r.Fprintln(color.Error, "Failed to load the configuration: File not present, got \""+configFile+"\" as the path.")
+		return
+	}
+
+	cfg := config.NewConfig()
+	if err := cfg.LoadSettings(configFile); err != nil {
+		log.Fatal("Failed to load the configuration file: ", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip blank lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		v := cfg.Explain(line)
+		if jsonOutput {
+			if err := enc.Encode(v); err != nil {
+				log.Fatal("Failed to encode the verdict: ", err)
+			}
+			continue
+		}
+
+		if v.InScope {
+			fmt.Println(g.Sprint("IN   ") + v.Value + y.Sprint(" "+ruleLabel(v)) + " " + v.Reason)
+		} else if v.Partial {
+			fmt.Println(y.Sprint("PART ") + v.Value + y.Sprint(" "+ruleLabel(v)) + " " + v.Reason)
+		} else {
+			fmt.Println(r.Sprint("OUT  ") + v.Value + y.Sprint(" "+ruleLabel(v)) + " " + v.Reason)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal("Failed to read from standard input: ", err)
+	}
+}
+
+// ruleLabel returns the scope section and entry that decided the verdict, or a dash when nothing matched.
+func ruleLabel(v *config.ScopeVerdict) string {
+	if v.Rule == "" {
+		return "[-]"
+	}
+	return "[" + v.Rule + ": " + v.Match + "]"
+}
@@ -30,6 +30,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 
 	"github.com/fatih/color"
 	"github.com/owasp-amass/config/config"
@@ -156,6 +157,12 @@ func main() {
 		options["alterations"] = alterations
 	}
 
+	// the ports are written using the same notation accepted for port ranges
+	var ports []string
+	for _, port := range iniConfig.Ports {
+		ports = append(ports, strconv.Itoa(port))
+	}
+
 	// this part of the code initializes the yamlconfig file with the values
 	yamlConfig := config.Config{
 		Scope: &config.Scope{
@@ -163,7 +170,7 @@ func main() {
 			IP:          iniConfig.Addresses,
 			ASNs:        iniConfig.ASNs,
 			CIDRStrings: iniConfig.CIDRs,
-			Ports:       iniConfig.Ports,
+			PortStrings: ports,
 			Blacklist:   iniConfig.Blacklist,
 		},
 		Options: options,
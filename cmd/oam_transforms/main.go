@@ -0,0 +1,139 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+// oam_transforms: Analyzes and draws the transformations for reachability, cycles and dead ends!
+//
+//	+----------------------------------------------------------------------------+
+//	| ░░░░░░░░░░░░░░░░░░░░░░░░░░░░░  OWASP Amass  ░░░░░░░░░░░░░░░░░░░░░░░░░░░░░░ |
+//	+----------------------------------------------------------------------------+
+//	|      .+++:.            :                             .+++.                 |
+//	|    +W@@@@@@8        &+W@#               o8W8:      +W@@@@@@#.   oW@@@W#+   |
+//	|   &@#+   .o@##.    .@@@o@W.o@@o       :@@#&W8o    .@#:  .:oW+  .@#+++&#&   |
+//	|  +@&        &@&     #@8 +@W@&8@+     :@W.   +@8   +@:          .@8         |
+//	|  8@          @@     8@o  8@8  WW    .@W      W@+  .@W.          o@#:       |
+//	|  WW          &@o    &@:  o@+  o@+   #@.      8@o   +W@#+.        +W@8:     |
+//	|  #@          :@W    &@+  &@+   @8  :@o       o@o     oW@@W+        oW@8    |
+//	|  o@+          @@&   &@+  &@+   #@  &@.      .W@W       .+#@&         o@W.  |
+//	|   WW         +@W@8. &@+  :&    o@+ #@      :@W&@&         &@:  ..     :@o  |
+//	|   :@W:      o@# +Wo &@+        :W: +@W&o++o@W. &@&  8@#o+&@W.  #@:    o@+  |
+//	|    :W@@WWWW@@8       +              :&W@@@@&    &W  .o#@@W&.   :W@WWW@@&   |
+//	|      +o&&&&+.                                                    +oooo.    |
+//	+----------------------------------------------------------------------------+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/owasp-amass/config/config"
+)
+
+const (
+	usageMsg = "-config path [options]"
+)
+
+var (
+	g = color.New(color.FgHiGreen)
+	r = color.New(color.FgHiRed)
+	y = color.New(color.FgHiYellow)
+)
+
+func main() {
+	var help1, help2, jsonOutput, dotOutput, mermaidOutput bool
+	var configFile, seedTypes string
+	transformsCommand := flag.NewFlagSet("transforms", flag.ContinueOnError)
+
+	transformsBuf := new(bytes.Buffer)
+	transformsCommand.SetOutput(transformsBuf)
+
+	transformsCommand.BoolVar(&help1, "h", false, "Show the program usage message")
+	transformsCommand.BoolVar(&help2, "help", false, "Show the program usage message")
+	transformsCommand.StringVar(&configFile, "config", "", "Path to the YAML configuration file.")
+	transformsCommand.StringVar(&seedTypes, "seed", "", "Comma-separated asset types to start from, instead of the types in the seed.")
+	transformsCommand.BoolVar(&jsonOutput, "json", false, "Print the analysis as JSON.")
+	transformsCommand.BoolVar(&dotOutput, "dot", false, "Print the transformations as a Graphviz DOT graph.")
+	transformsCommand.BoolVar(&mermaidOutput, "mermaid", false, "Print the transformations as a Mermaid flowchart.")
+
+	var usage = func() {
+		g.Fprintf(color.Error, "Usage: %s %s\n\n", path.Base(os.Args[0]), usageMsg)
+		transformsCommand.PrintDefaults()
+		g.Fprintln(color.Error, transformsBuf.String())
+	}
+
+	if len(os.Args) < 2 {
+		usage()
+		return
+	}
+	if err := transformsCommand.Parse(os.Args[1:]); err != nil {
+		r.Fprintf(color.Error, "%v\n", err)
+		os.Exit(1)
+	}
+	if help1 || help2 {
+		usage()
+		return
+	}
+	if configFile == "" {
+		usage()
+		r.Fprintln(color.Error, "Failed to load the configuration: File not present, got \""+configFile+"\" as the path.")
+		return
+	}
+
+	cfg := config.NewConfig()
+	if err := cfg.LoadSettings(configFile); err != nil {
+		log.Fatal("Failed to load the configuration file: ", err)
+	}
+
+	if dotOutput {
+		if err := cfg.WriteTransformDOT(os.Stdout); err != nil {
+			log.Fatal("Failed to write the DOT graph: ", err)
+		}
+		return
+	}
+	if mermaidOutput {
+		if err := cfg.WriteTransformMermaid(os.Stdout); err != nil {
+			log.Fatal("Failed to write the Mermaid flowchart: ", err)
+		}
+		return
+	}
+
+	var seeds []string
+	for _, s := range strings.Split(seedTypes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			seeds = append(seeds, s)
+		}
+	}
+
+	a, err := cfg.AnalyzeTransformations(seeds...)
+	if err != nil {
+		log.Fatal("Failed to analyze the transformations: ", err)
+	}
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(a); err != nil {
+			log.Fatal("Failed to encode the analysis: ", err)
+		}
+		return
+	}
+
+	fmt.Println(g.Sprint("SEED TYPES   ") + strings.Join(a.Seeds, ", "))
+	fmt.Println(g.Sprint("REACHABLE    ") + strings.Join(a.Reachable, ", "))
+	for _, rule := range a.Unreachable {
+		fmt.Println(r.Sprint("UNREACHABLE  ") + rule)
+	}
+	for _, c := range a.Cycles {
+		fmt.Println(y.Sprint("CYCLE        ") + strings.Join(c.Path, "->") + " [" + strings.Join(c.Types, ", ") + "]")
+	}
+	for _, t := range a.DeadEnds {
+		fmt.Println(y.Sprint("DEAD END     ") + t)
+	}
+}
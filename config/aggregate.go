@@ -0,0 +1,157 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// aggregateNetworks normalizes the address scope. Nested, duplicate and adjacent CIDRs are aggregated
+// into the fewest prefixes, and the ips entries covered by a CIDR or another ips entry are removed.
+// The order of the entries is kept, and invalid entries are left for populate to report. The returned
+// descriptions identify the entries that were redundant.
+func (s *Scope) aggregateNetworks() []string {
+	cidrs, redundant := s.aggregateCIDRs()
+
+	// The aggregated prefixes are sorted, and neither overlap nor need to be merged
+	var coverage []addrRange
+	for _, p := range cidrs {
+		coverage = append(coverage, prefixRange(p))
+	}
+
+	type ipsEntry struct {
+		index int
+		r     addrRange
+	}
+
+	var entries []ipsEntry
+	for i, entry := range s.IP {
+		if r, err := parseAddrRange(entry); err == nil {
+			entries = append(entries, ipsEntry{index: i, r: r})
+		}
+	}
+	// Each entry is compared with those starting before it, and the wider entry comes first
+	sort.SliceStable(entries, func(i, j int) bool {
+		if c := entries[i].r.start.Compare(entries[j].r.start); c != 0 {
+			return c < 0
+		}
+		return entries[i].r.end.Compare(entries[j].r.end) > 0
+	})
+
+	covered := make(map[int]string)
+	var widest *ipsEntry
+	for i := range entries {
+		e := &entries[i]
+
+		if p, found := coveringPrefix(e.r, cidrs, coverage); found {
+			covered[e.index] = p.String()
+			continue
+		}
+		if widest != nil && widest.r.start.Is4() == e.r.start.Is4() && e.r.end.Compare(widest.r.end) <= 0 {
+			covered[e.index] = strings.TrimSpace(s.IP[widest.index])
+			continue
+		}
+		if widest == nil || widest.r.start.Is4() != e.r.start.Is4() || e.r.end.Compare(widest.r.end) > 0 {
+			widest = e
+		}
+	}
+
+	var kept []string
+	for i, entry := range s.IP {
+		if by, found := covered[i]; found {
+			redundant = append(redundant, fmt.Sprintf("%s is covered by %s", strings.TrimSpace(entry), by))
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	s.IP = kept
+	return redundant
+}
+
+// aggregateCIDRs replaces the CIDR entries with the aggregated prefixes, and returns the sorted prefixes
+// along with the descriptions of the entries that were redundant or aggregated.
+func (s *Scope) aggregateCIDRs() ([]netip.Prefix, []string) {
+	original := make(map[netip.Prefix]struct{})
+	var ranges []addrRange
+	for _, entry := range s.CIDRStrings {
+		if p, ok := parseCIDREntry(entry); ok {
+			original[p] = struct{}{}
+			ranges = append(ranges, prefixRange(p))
+		}
+	}
+
+	var aggregated []netip.Prefix
+	for _, r := range mergeRanges(ranges) {
+		aggregated = append(aggregated, r.prefixes()...)
+	}
+	coverage := make([]addrRange, 0, len(aggregated))
+	included := make(map[netip.Prefix]struct{}, len(aggregated))
+	for _, p := range aggregated {
+		coverage = append(coverage, prefixRange(p))
+		included[p] = struct{}{}
+	}
+
+	var entries, results []string
+	emitted := make(map[netip.Prefix]struct{})
+	for _, entry := range s.CIDRStrings {
+		p, ok := parseCIDREntry(entry)
+		if !ok {
+			entries = append(entries, entry)
+			continue
+		}
+
+		if _, found := included[p]; found {
+			if _, dup := emitted[p]; dup {
+				results = append(results, fmt.Sprintf("%s is a duplicate of %s", strings.TrimSpace(entry), p))
+				continue
+			}
+			emitted[p] = struct{}{}
+			entries = append(entries, entry)
+			continue
+		}
+
+		q, found := coveringPrefix(prefixRange(p), aggregated, coverage)
+		if !found {
+			entries = append(entries, entry)
+			continue
+		}
+		if _, found := original[q]; found {
+			results = append(results, fmt.Sprintf("%s is covered by %s", strings.TrimSpace(entry), q))
+			continue
+		}
+		results = append(results, fmt.Sprintf("%s was aggregated into %s", strings.TrimSpace(entry), q))
+		if _, found := emitted[q]; !found {
+			emitted[q] = struct{}{}
+			entries = append(entries, q.String())
+		}
+	}
+
+	s.CIDRStrings = entries
+	return aggregated, results
+}
+
+// parseCIDREntry parses the CIDR entry into the network prefix, unmapping IPv4 addresses.
+func parseCIDREntry(entry string) (netip.Prefix, bool) {
+	_, ipnet, err := net.ParseCIDR(strings.TrimSpace(entry))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return ipNetToPrefix(ipnet)
+}
+
+// coveringPrefix returns the prefix from the sorted list that covers the whole range. The ranges
+// hold the addresses covered by each of the prefixes.
+func coveringPrefix(r addrRange, prefixes []netip.Prefix, ranges []addrRange) (netip.Prefix, bool) {
+	// Find the last prefix starting at or before the range
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].start.Compare(r.start) > 0 }) - 1
+	if i >= 0 && ranges[i].start.Is4() == r.start.Is4() && ranges[i].end.Compare(r.end) >= 0 {
+		return prefixes[i], true
+	}
+	return netip.Prefix{}, false
+}
@@ -0,0 +1,131 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAggregateNetworks(t *testing.T) {
+	tests := []struct {
+		name      string
+		cidrs     []string
+		ips       []string
+		wantCIDRs []string
+		wantIPs   []string
+		redundant []string
+	}{
+		{
+			name:      "nested prefix",
+			cidrs:     []string{"192.0.2.0/24", "192.0.2.128/25"},
+			wantCIDRs: []string{"192.0.2.0/24"},
+			redundant: []string{"192.0.2.128/25 is covered by 192.0.2.0/24"},
+		},
+		{
+			name:      "adjacent prefixes",
+			cidrs:     []string{"198.51.100.128/25", "10.0.0.0/8", "198.51.100.0/25"},
+			wantCIDRs: []string{"198.51.100.0/24", "10.0.0.0/8"},
+			redundant: []string{
+				"198.51.100.128/25 was aggregated into 198.51.100.0/24",
+				"198.51.100.0/25 was aggregated into 198.51.100.0/24",
+			},
+		},
+		{
+			name:      "adjacent prefixes that cannot be aggregated",
+			cidrs:     []string{"192.0.3.0/24", "192.0.4.0/24"},
+			wantCIDRs: []string{"192.0.3.0/24", "192.0.4.0/24"},
+		},
+		{
+			name:      "duplicate prefix with host bits",
+			cidrs:     []string{"203.0.113.0/24", "203.0.113.7/24", "2001:db8::/32", "2001:db8:1::/48"},
+			wantCIDRs: []string{"203.0.113.0/24", "2001:db8::/32"},
+			redundant: []string{
+				"203.0.113.7/24 is a duplicate of 203.0.113.0/24",
+				"2001:db8:1::/48 is covered by 2001:db8::/32",
+			},
+		},
+		{
+			name:      "addresses covered by a prefix",
+			cidrs:     []string{"192.0.2.0/24"},
+			ips:       []string{"192.0.2.1", "192.0.2.250-192.0.3.5", "192.0.2.10-20", "198.51.100.1"},
+			wantCIDRs: []string{"192.0.2.0/24"},
+			wantIPs:   []string{"192.0.2.250-192.0.3.5", "198.51.100.1"},
+			redundant: []string{
+				"192.0.2.1 is covered by 192.0.2.0/24",
+				"192.0.2.10-20 is covered by 192.0.2.0/24",
+			},
+		},
+		{
+			name:      "addresses covered by other entries",
+			ips:       []string{"10.1.2.48", "10.1.2.46-50", "10.1.2.46-50", "10.1.2.50-10.1.2.65", "2001:db8::1"},
+			wantIPs:   []string{"10.1.2.46-50", "10.1.2.50-10.1.2.65", "2001:db8::1"},
+			redundant: []string{"10.1.2.48 is covered by 10.1.2.46-50", "10.1.2.46-50 is covered by 10.1.2.46-50"},
+		},
+		{
+			name:      "invalid entries are kept",
+			cidrs:     []string{"192.0.2.0/33", "192.0.2.0/24"},
+			ips:       []string{"192.0.2"},
+			wantCIDRs: []string{"192.0.2.0/33", "192.0.2.0/24"},
+			wantIPs:   []string{"192.0.2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scope{CIDRStrings: tt.cidrs, IP: tt.ips}
+
+			redundant := s.aggregateNetworks()
+			if !reflect.DeepEqual(s.CIDRStrings, tt.wantCIDRs) {
+				t.Errorf("CIDRStrings = %v, want %v", s.CIDRStrings, tt.wantCIDRs)
+			}
+			if !reflect.DeepEqual(s.IP, tt.wantIPs) {
+				t.Errorf("IP = %v, want %v", s.IP, tt.wantIPs)
+			}
+			if !reflect.DeepEqual(redundant, tt.redundant) {
+				t.Errorf("aggregateNetworks() = %v, want %v", redundant, tt.redundant)
+			}
+		})
+	}
+}
+
+func TestLoadAggregatedNetworks(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  ips:
+    - 192.0.2.1
+    - 192.0.2.2
+  cidrs:
+    - 192.0.2.0/24
+    - 192.0.2.128/25`), c); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	c.Log = log.New(&buf, "", 0)
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"192.0.2.0/24"}; !reflect.DeepEqual(c.Scope.CIDRStrings, want) {
+		t.Errorf("CIDRStrings = %v, want %v", c.Scope.CIDRStrings, want)
+	}
+	if len(c.Scope.IP) != 0 || len(c.Scope.Addresses) != 0 {
+		t.Errorf("the covered addresses were kept: %v", c.Scope.IP)
+	}
+	if !c.IsAddressInScope("192.0.2.1") {
+		t.Errorf("IsAddressInScope() = false for an address within the aggregated scope")
+	}
+	for _, entry := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.128/25"} {
+		if !strings.Contains(buf.String(), entry+" is covered by 192.0.2.0/24") {
+			t.Errorf("the warning %q does not list %s", buf.String(), entry)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	neturl "net/url"
+	"strings"
+)
+
+// The ports used by the URL schemes when the port is not provided.
+var defaultSchemePorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// populateAssets validates and normalizes the organizations, email addresses and URLs in the scope.
+func (s *Scope) populateAssets() error {
+	var orgs []string
+	for _, o := range s.Organizations {
+		name := normalizeOrganization(o)
+		if name == "" {
+			return fmt.Errorf("the organization name cannot be empty")
+		}
+		orgs = append(orgs, name)
+	}
+	s.Organizations = sortedSet(orgs)
+
+	var emails []string
+	for _, e := range s.Emails {
+		email, err := NormalizeEmail(e)
+		if err != nil {
+			return err
+		}
+		emails = append(emails, email)
+	}
+	s.Emails = sortedSet(emails)
+
+	var prefixes []*neturl.URL
+	for _, u := range s.URLs {
+		prefix, err := parseURLPrefix(u)
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	s.URLPrefixes = prefixes
+	return nil
+}
+
+// normalizeOrganization lower-cases the organization name and collapses the whitespace.
+func normalizeOrganization(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// NormalizeEmail lower-cases the email address and converts the domain into the A-label (punycode)
+// form. An address starting with @, such as @example.com, represents every mailbox at the domain.
+func NormalizeEmail(email string) (string, error) {
+	local, domain, found := strings.Cut(strings.TrimSpace(email), "@")
+	if !found || strings.Contains(domain, "@") || strings.ContainsAny(local, " \t") {
+		return "", fmt.Errorf("%s is not a valid email address", email)
+	}
+
+	d, err := NormalizeDomain(domain)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a valid email address: %v", email, err)
+	}
+	return strings.ToLower(local) + "@" + d, nil
+}
+
+// parseURLPrefix parses and normalizes a URL in scope. The scheme and host are required,
+// and the path is used as a prefix for the URLs in scope.
+func parseURLPrefix(raw string) (*neturl.URL, error) {
+	u, err := neturl.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid URL: %v", raw, err)
+	}
+	if u.Scheme == "" || u.Hostname() == "" {
+		return nil, fmt.Errorf("%s is not a valid URL: the scheme and host are required", raw)
+	}
+
+	host, err := normalizeURLHost(u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid URL: %v", raw, err)
+	}
+
+	return &neturl.URL{
+		Scheme: strings.ToLower(u.Scheme),
+		Host:   urlHostPort(strings.ToLower(u.Scheme), host, u.Port()),
+		Path:   u.EscapedPath(),
+	}, nil
+}
+
+func normalizeURLHost(host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	return NormalizeDomain(host)
+}
+
+// urlHostPort joins the host and port, using the default port of the scheme when none is provided.
+func urlHostPort(scheme, host, port string) string {
+	if port == "" {
+		port = defaultSchemePorts[scheme]
+	}
+	if port == "" {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// hasURLPathPrefix returns true if the path is equal to the prefix or below it.
+func hasURLPathPrefix(path, prefix string) bool {
+	if prefix == "" || prefix == "/" || path == prefix {
+		return true
+	}
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(path, prefix)
+	}
+	return strings.HasPrefix(path, prefix+"/")
+}
+
+// WhichOrganization returns the organization in the config list that matches the name in the parameter.
+// The names are compared without regard to case or repeated whitespace.
+func (c *Config) WhichOrganization(name string) string {
+	n := normalizeOrganization(name)
+	if n == "" {
+		return ""
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.Scope == nil {
+		return ""
+	}
+	for _, o := range c.Scope.Organizations {
+		if o == n {
+			return o
+		}
+	}
+	return ""
+}
+
+// IsOrganizationInScope returns true if the name in the parameter matches an organization in the config list.
+func (c *Config) IsOrganizationInScope(name string) bool {
+	return c.WhichOrganization(name) != ""
+}
+
+// WhichEmail returns the entry in the config list of email addresses that matches the address in the
+// parameter. Entries starting with @ match every mailbox at the domain.
+func (c *Config) WhichEmail(email string) string {
+	e, err := NormalizeEmail(email)
+	if err != nil {
+		return ""
+	}
+	_, domain, _ := strings.Cut(e, "@")
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.Scope == nil {
+		return ""
+	}
+	for _, entry := range c.Scope.Emails {
+		if entry == e || entry == "@"+domain {
+			return entry
+		}
+	}
+	return ""
+}
+
+// IsEmailInScope returns true if the email address in the parameter matches an entry in the config list
+// of email addresses, or the domain of the address is in scope.
+func (c *Config) IsEmailInScope(email string) bool {
+	if c.WhichEmail(email) != "" {
+		return true
+	}
+
+	if _, domain, found := strings.Cut(email, "@"); found {
+		return c.IsDomainInScope(domain) && !c.Blacklisted(domain)
+	}
+	return false
+}
+
+// WhichURL returns the entry in the config list of URLs that the URL in the parameter falls within.
+// The scheme, host and port must match the entry, and the path must be equal to or below the entry path.
+func (c *Config) WhichURL(rawURL string) string {
+	u, err := neturl.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+
+	host, err := normalizeURLHost(u.Hostname())
+	if err != nil {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	hostport := urlHostPort(scheme, host, u.Port())
+	path := u.EscapedPath()
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.Scope == nil {
+		return ""
+	}
+	for i, prefix := range c.Scope.URLPrefixes {
+		if prefix.Scheme == scheme && prefix.Host == hostport && hasURLPathPrefix(path, prefix.Path) {
+			if i < len(c.Scope.URLs) {
+				return c.Scope.URLs[i]
+			}
+			return prefix.String()
+		}
+	}
+	return ""
+}
+
+// IsURLInScope returns true if the URL in the parameter falls within an entry in the config list of URLs,
+// or the host of the URL is in scope.
+func (c *Config) IsURLInScope(rawURL string) bool {
+	if c.WhichURL(rawURL) != "" {
+		return true
+	}
+
+	u, err := neturl.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		return c.IsAddressInScope(ip.String())
+	}
+	return c.IsDomainInScope(u.Hostname()) && !c.Blacklisted(u.Hostname())
+}
@@ -0,0 +1,199 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/owasp-amass/open-asset-model/contact"
+	"github.com/owasp-amass/open-asset-model/org"
+	"github.com/owasp-amass/open-asset-model/url"
+	"gopkg.in/yaml.v3"
+)
+
+func loadAssetScope(t *testing.T) *Config {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - owasp.org
+  blacklist:
+    - internal.owasp.org
+  organizations:
+    - "  OWASP   Foundation "
+  emails:
+    - Security@Example.com
+    - "@example.net"
+  urls:
+    - https://app.example.com/portal/
+    - http://Example.org:8080/api
+    - https://192.0.2.10`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+	c.AddDomains(c.Scope.Domains...)
+	return c
+}
+
+func TestPopulateAssets(t *testing.T) {
+	c := loadAssetScope(t)
+
+	if want := []string{"owasp foundation"}; !reflect.DeepEqual(c.Scope.Organizations, want) {
+		t.Errorf("Organizations = %v, want %v", c.Scope.Organizations, want)
+	}
+	if want := []string{"@example.net", "security@example.com"}; !reflect.DeepEqual(c.Scope.Emails, want) {
+		t.Errorf("Emails = %v, want %v", c.Scope.Emails, want)
+	}
+
+	var prefixes []string
+	for _, p := range c.Scope.URLPrefixes {
+		prefixes = append(prefixes, p.String())
+	}
+	want := []string{"https://app.example.com:443/portal/", "http://example.org:8080/api", "https://192.0.2.10:443"}
+	if !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("URLPrefixes = %v, want %v", prefixes, want)
+	}
+}
+
+func TestPopulateAssetsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope *Scope
+	}{
+		{name: "empty organization", scope: &Scope{Organizations: []string{"  "}}},
+		{name: "email without domain", scope: &Scope{Emails: []string{"security"}}},
+		{name: "email with invalid domain", scope: &Scope{Emails: []string{"a@example..com"}}},
+		{name: "url without scheme", scope: &Scope{URLs: []string{"app.example.com/portal"}}},
+		{name: "url with invalid host", scope: &Scope{URLs: []string{"https://example..com/"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.scope.populateAssets(); err == nil {
+				t.Errorf("populateAssets() expected an error")
+			}
+		})
+	}
+}
+
+func TestWhichOrganization(t *testing.T) {
+	c := loadAssetScope(t)
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "different case and spacing", value: "owasp foundation", want: "owasp foundation"},
+		{name: "other organization", value: "OWASP Foundation Inc"},
+		{name: "empty name", value: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.WhichOrganization(tt.value); got != tt.want {
+				t.Errorf("WhichOrganization() = %s, want %s", got, tt.want)
+			}
+			if got := c.IsOrganizationInScope(tt.value); got != (tt.want != "") {
+				t.Errorf("IsOrganizationInScope() = %v, want %v", got, tt.want != "")
+			}
+		})
+	}
+}
+
+func TestWhichEmail(t *testing.T) {
+	c := loadAssetScope(t)
+
+	tests := []struct {
+		name    string
+		value   string
+		match   string
+		inscope bool
+	}{
+		{name: "listed address", value: "security@EXAMPLE.com", match: "security@example.com", inscope: true},
+		{name: "other mailbox at a listed address domain", value: "info@example.com"},
+		{name: "mailbox at a listed domain", value: "anyone@example.net", match: "@example.net", inscope: true},
+		{name: "mailbox at a subdomain of a listed domain", value: "anyone@mail.example.net"},
+		{name: "mailbox at a domain in scope", value: "info@owasp.org", inscope: true},
+		{name: "mailbox at a blacklisted domain", value: "info@internal.owasp.org"},
+		{name: "invalid address", value: "owasp.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.WhichEmail(tt.value); got != tt.match {
+				t.Errorf("WhichEmail() = %s, want %s", got, tt.match)
+			}
+			if got := c.IsEmailInScope(tt.value); got != tt.inscope {
+				t.Errorf("IsEmailInScope() = %v, want %v", got, tt.inscope)
+			}
+		})
+	}
+}
+
+func TestWhichURL(t *testing.T) {
+	c := loadAssetScope(t)
+
+	tests := []struct {
+		name    string
+		value   string
+		match   string
+		inscope bool
+	}{
+		{name: "path below the prefix", value: "https://APP.example.com/portal/login?next=1", match: "https://app.example.com/portal/", inscope: true},
+		{name: "explicit default port", value: "https://app.example.com:443/portal/", match: "https://app.example.com/portal/", inscope: true},
+		{name: "path outside the prefix", value: "https://app.example.com/admin"},
+		{name: "different scheme", value: "http://app.example.com/portal/"},
+		{name: "prefix without a trailing slash", value: "http://example.org:8080/api/v1/users", match: "http://Example.org:8080/api", inscope: true},
+		{name: "path sharing the prefix characters", value: "http://example.org:8080/apix"},
+		{name: "different port", value: "http://example.org/api"},
+		{name: "address host", value: "https://192.0.2.10/anything", match: "https://192.0.2.10", inscope: true},
+		{name: "host in scope", value: "https://www.owasp.org/index.html", inscope: true},
+		{name: "blacklisted host", value: "https://vpn.internal.owasp.org/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.WhichURL(tt.value); got != tt.match {
+				t.Errorf("WhichURL() = %s, want %s", got, tt.match)
+			}
+			if got := c.IsURLInScope(tt.value); got != tt.inscope {
+				t.Errorf("IsURLInScope() = %v, want %v", got, tt.inscope)
+			}
+		})
+	}
+}
+
+func TestInScopeAssets(t *testing.T) {
+	c := loadAssetScope(t)
+
+	if v := c.InScope(&org.Organization{Name: "OWASP Foundation"}); !v.InScope || v.Rule != "organizations" {
+		t.Errorf("InScope() = %v with rule %s, want true with rule organizations", v.InScope, v.Rule)
+	}
+	if v := c.InScope(&contact.EmailAddress{Address: "a@example.net"}); !v.InScope || v.Match != "@example.net" {
+		t.Errorf("InScope() = %v with match %s, want true with match @example.net", v.InScope, v.Match)
+	}
+	u := &url.URL{Raw: "https://app.example.com/portal/x", Scheme: "https", Host: "app.example.com", Path: "/portal/x"}
+	if v := c.InScope(u); !v.InScope || v.Rule != "urls" {
+		t.Errorf("InScope() = %v with rule %s, want true with rule urls", v.InScope, v.Rule)
+	}
+	if v := c.Explain("https://app.example.com/other"); v.InScope {
+		t.Errorf("Explain() = true, want false for a URL outside the prefix")
+	}
+}
+
+func TestAssetsWithoutScope(t *testing.T) {
+	c := NewConfig()
+	c.Scope = nil
+
+	if got := c.WhichOrganization("OWASP Foundation"); got != "" {
+		t.Errorf("WhichOrganization() = %s without a scope", got)
+	}
+	if got := c.WhichEmail("jeff@owasp.org"); got != "" {
+		t.Errorf("WhichEmail() = %s without a scope", got)
+	}
+	if got := c.WhichURL("https://www.owasp.org/index.html"); got != "" {
+		t.Errorf("WhichURL() = %s without a scope", got)
+	}
+}
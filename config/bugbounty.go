@@ -0,0 +1,422 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/caffix/stringset"
+)
+
+// The asset types found in HackerOne structured scopes that can be mapped into the scope.
+var hackerOneAssetTypes = map[string]struct{}{
+	"url":        {},
+	"wildcard":   {},
+	"domain":     {},
+	"cidr":       {},
+	"ip_address": {},
+}
+
+// The target types found in Bugcrowd program exports that can be mapped into the scope.
+var bugcrowdTargetTypes = map[string]struct{}{
+	"website":    {},
+	"api":        {},
+	"ip_address": {},
+	"network":    {},
+}
+
+// hackerOneScope represents a HackerOne structured scope, as found in the API and program exports.
+type hackerOneScope struct {
+	AssetType             string `json:"asset_type"`
+	AssetIdentifier       string `json:"asset_identifier"`
+	EligibleForSubmission *bool  `json:"eligible_for_submission"`
+}
+
+// hackerOneExport represents the JSON formats exported for a HackerOne program.
+type hackerOneExport struct {
+	// The format returned by the HackerOne structured scopes API
+	Data []struct {
+		Attributes hackerOneScope `json:"attributes"`
+	} `json:"data"`
+	// The format used by the program target exports
+	Targets struct {
+		InScope    []hackerOneScope `json:"in_scope"`
+		OutOfScope []hackerOneScope `json:"out_of_scope"`
+	} `json:"targets"`
+}
+
+// bugcrowdTarget represents a Bugcrowd program target.
+type bugcrowdTarget struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Name   string `json:"name"`
+}
+
+// bugcrowdExport represents the JSON format exported for a Bugcrowd program.
+type bugcrowdExport struct {
+	Targets struct {
+		InScope    []bugcrowdTarget `json:"in_scope"`
+		OutOfScope []bugcrowdTarget `json:"out_of_scope"`
+	} `json:"targets"`
+}
+
+// scopeImporter builds a Scope from the targets found in a bug bounty program export.
+type scopeImporter struct {
+	scope   *Scope
+	skipped []string
+}
+
+// ImportHackerOneScope reads a HackerOne program scope export, in CSV or JSON format, from the file
+// at the path provided. It returns the resulting Scope and the targets that could not be mapped into it.
+func ImportHackerOneScope(path string) (*Scope, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the HackerOne scope export: %v", err)
+	}
+
+	imp := newScopeImporter()
+	if isJSONData(data) {
+		err = imp.hackerOneJSON(data)
+	} else {
+		err = imp.hackerOneCSV(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the HackerOne scope export: %v", err)
+	}
+	return imp.finish()
+}
+
+// ImportBugcrowdScope reads a Bugcrowd program scope export, in CSV or JSON format, from the file
+// at the path provided. It returns the resulting Scope and the targets that could not be mapped into it.
+func ImportBugcrowdScope(path string) (*Scope, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the Bugcrowd scope export: %v", err)
+	}
+
+	imp := newScopeImporter()
+	if isJSONData(data) {
+		err = imp.bugcrowdJSON(data)
+	} else {
+		err = imp.bugcrowdCSV(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the Bugcrowd scope export: %v", err)
+	}
+	return imp.finish()
+}
+
+func newScopeImporter() *scopeImporter {
+	return &scopeImporter{scope: &Scope{}}
+}
+
+func isJSONData(data []byte) bool {
+	d := bytes.TrimSpace(data)
+	return len(d) > 0 && (d[0] == '{' || d[0] == '[')
+}
+
+func (imp *scopeImporter) hackerOneJSON(data []byte) error {
+	var export hackerOneExport
+	var scopes []hackerOneScope
+
+	// The API format can also be provided as a plain list of structured scopes
+	if bytes.TrimSpace(data)[0] == '[' {
+		if err := json.Unmarshal(data, &scopes); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(data, &export); err != nil {
+		return err
+	}
+
+	for _, d := range export.Data {
+		scopes = append(scopes, d.Attributes)
+	}
+	for _, s := range scopes {
+		imp.hackerOneTarget(s.AssetType, s.AssetIdentifier, s.EligibleForSubmission == nil || *s.EligibleForSubmission)
+	}
+	for _, s := range export.Targets.InScope {
+		imp.hackerOneTarget(s.AssetType, s.AssetIdentifier, true)
+	}
+	for _, s := range export.Targets.OutOfScope {
+		imp.hackerOneTarget(s.AssetType, s.AssetIdentifier, false)
+	}
+	return nil
+}
+
+func (imp *scopeImporter) hackerOneCSV(r io.Reader) error {
+	records, err := readCSVRecords(r)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		inscope := true
+		if v, found := rec["eligible_for_submission"]; found && v != "" {
+			inscope, _ = strconv.ParseBool(v)
+		}
+		imp.hackerOneTarget(rec["asset_type"], rec["identifier"], inscope)
+	}
+	return nil
+}
+
+func (imp *scopeImporter) hackerOneTarget(atype, target string, inscope bool) {
+	if _, found := hackerOneAssetTypes[strings.ToLower(strings.TrimSpace(atype))]; !found {
+		imp.skipped = append(imp.skipped, target)
+		return
+	}
+	imp.addTarget(target, inscope)
+}
+
+func (imp *scopeImporter) bugcrowdJSON(data []byte) error {
+	var export bugcrowdExport
+
+	if err := json.Unmarshal(data, &export); err != nil {
+		return err
+	}
+
+	for _, t := range export.Targets.InScope {
+		imp.bugcrowdTarget(t, true)
+	}
+	for _, t := range export.Targets.OutOfScope {
+		imp.bugcrowdTarget(t, false)
+	}
+	return nil
+}
+
+func (imp *scopeImporter) bugcrowdCSV(r io.Reader) error {
+	records, err := readCSVRecords(r)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		t := bugcrowdTarget{
+			Type:   rec["type"],
+			Target: rec["target"],
+			Name:   rec["name"],
+		}
+		if t.Type == "" {
+			t.Type = rec["category"]
+		}
+
+		inscope := true
+		if v, found := rec["in_scope"]; found && v != "" {
+			inscope, _ = strconv.ParseBool(v)
+		} else if v, found := rec["scope"]; found {
+			inscope = !strings.Contains(strings.ToLower(v), "out")
+		}
+		imp.bugcrowdTarget(t, inscope)
+	}
+	return nil
+}
+
+func (imp *scopeImporter) bugcrowdTarget(t bugcrowdTarget, inscope bool) {
+	target := t.Target
+	if target == "" {
+		target = t.Name
+	}
+
+	if ttype := strings.ToLower(strings.TrimSpace(t.Type)); ttype != "" {
+		if _, found := bugcrowdTargetTypes[ttype]; !found {
+			imp.skipped = append(imp.skipped, target)
+			return
+		}
+	}
+	imp.addTarget(target, inscope)
+}
+
+// readCSVRecords reads the CSV data and returns each row keyed by the lower-cased header names.
+func readCSVRecords(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CSV header: %v", err)
+	}
+	for i, h := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+
+	var records []map[string]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rec := make(map[string]string, len(header))
+		for i, v := range row {
+			if i < len(header) {
+				rec[header[i]] = strings.TrimSpace(v)
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// addTarget maps a program target into the scope domains, URLs, addresses, CIDRs, blacklist and exclusions.
+func (imp *scopeImporter) addTarget(target string, inscope bool) {
+	t := strings.TrimSpace(target)
+	if t == "" {
+		return
+	}
+	// Multiple targets are sometimes provided in the same entry
+	if strings.ContainsAny(t, ", ") {
+		for _, f := range strings.FieldsFunc(t, func(r rune) bool { return r == ',' || r == ' ' }) {
+			imp.addTarget(f, inscope)
+		}
+		return
+	}
+
+	if _, ipnet, err := net.ParseCIDR(t); err == nil {
+		if inscope {
+			imp.scope.CIDRStrings = append(imp.scope.CIDRStrings, ipnet.String())
+		} else {
+			imp.scope.Exclusions = append(imp.scope.Exclusions, ipnet.String())
+		}
+		return
+	}
+	if ip := net.ParseIP(t); ip != nil {
+		if inscope {
+			imp.scope.IP = append(imp.scope.IP, ip.String())
+		} else {
+			imp.scope.Exclusions = append(imp.scope.Exclusions, ip.String())
+		}
+		return
+	}
+	// The range is validated without expanding the addresses
+	if r, err := parseAddrRange(t); strings.Contains(t, "-") && err == nil {
+		if inscope {
+			imp.scope.IP = append(imp.scope.IP, t)
+		} else {
+			// Exclusions only accept addresses and CIDRs, so the range is split into the CIDRs covering it
+			for _, p := range r.prefixes() {
+				imp.scope.Exclusions = append(imp.scope.Exclusions, p.String())
+			}
+		}
+		return
+	}
+	if targetHasPath(t) {
+		// An in scope path is limited to the URLs under it, instead of the host and all of its subdomains
+		if inscope {
+			if u, err := parseURLPrefix(t); err == nil && !strings.ContainsAny(t, "?#") {
+				imp.scope.URLs = append(imp.scope.URLs, u.String())
+				return
+			}
+		}
+		// Blacklisting the host of an out of scope path would also remove the rest of the host from the scope
+		imp.skipped = append(imp.skipped, target)
+		return
+	}
+
+	name := targetDomainName(t)
+	if name == "" {
+		imp.skipped = append(imp.skipped, target)
+		return
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		imp.addTarget(name, inscope)
+		return
+	}
+
+	if inscope {
+		imp.scope.Domains = append(imp.scope.Domains, name)
+	} else {
+		imp.scope.Blacklist = append(imp.scope.Blacklist, name)
+	}
+}
+
+// targetDomainName extracts the DNS name from a wildcard, URL or domain name target.
+// An empty string is returned when the target cannot be represented as a DNS name.
+func targetDomainName(target string) string {
+	t := target
+
+	if strings.Contains(t, "://") {
+		u, err := url.Parse(t)
+		if err != nil {
+			return ""
+		}
+		t = u.Hostname()
+	} else {
+		// Remove any path or port following the host
+		if i := strings.IndexAny(t, "/?#"); i >= 0 {
+			t = t[:i]
+		}
+		if host, _, err := net.SplitHostPort(t); err == nil {
+			t = host
+		}
+	}
+
+	t = strings.TrimPrefix(t, "*.")
+	if t == "" || strings.ContainsAny(t, "*_ ") {
+		return ""
+	}
+	if ip := net.ParseIP(t); ip != nil {
+		return ip.String()
+	}
+
+	name, err := NormalizeDomain(t)
+	if err != nil || len(strings.Split(name, ".")) < 2 {
+		return ""
+	}
+	return name
+}
+
+// targetHasPath returns true if the target is limited to a path, query or fragment within the host.
+// A target ending with the root path, such as https://www.owasp.org/ or www.owasp.org/*, covers the host.
+func targetHasPath(target string) bool {
+	t := target
+	if _, after, found := strings.Cut(t, "://"); found {
+		t = after
+	}
+
+	i := strings.IndexAny(t, "/?#")
+	if i < 0 {
+		return false
+	}
+	switch t[i:] {
+	case "/", "/*":
+		return false
+	}
+	return true
+}
+
+func (imp *scopeImporter) finish() (*Scope, []string, error) {
+	s := imp.scope
+
+	s.Domains = sortedSet(s.Domains)
+	s.IP = sortedSet(s.IP)
+	s.CIDRStrings = sortedSet(s.CIDRStrings)
+	s.Blacklist = sortedSet(s.Blacklist)
+	s.Exclusions = sortedSet(s.Exclusions)
+	s.URLs = sortedSet(s.URLs)
+
+	if s.isScopeEmpty(false) {
+		return nil, imp.skipped, fmt.Errorf("the export did not contain any targets that could be mapped into the scope")
+	}
+	return s, imp.skipped, nil
+}
+
+// sortedSet returns the deduplicated elements in sorted order, so the resulting files are stable.
+func sortedSet(elements []string) []string {
+	set := stringset.Deduplicate(elements)
+	sort.Strings(set)
+	return set
+}
@@ -0,0 +1,187 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var hackerOneCSV = []byte(`identifier,asset_type,instruction,eligible_for_bounty,eligible_for_submission,availability_requirement,confidentiality_requirement,integrity_requirement,max_severity,system_tags,created_at,updated_at
+*.owasp.org,WILDCARD,,true,true,,,,critical,,2024-01-01 00:00:00 UTC,2024-01-01 00:00:00 UTC
+https://app.example.com/login,URL,,true,true,,,,critical,,2024-01-01 00:00:00 UTC,2024-01-01 00:00:00 UTC
+192.0.2.0/24,CIDR,,true,true,,,,critical,,2024-01-01 00:00:00 UTC,2024-01-01 00:00:00 UTC
+198.51.100.7,IP_ADDRESS,,true,true,,,,critical,,2024-01-01 00:00:00 UTC,2024-01-01 00:00:00 UTC
+com.example.app,GOOGLE_PLAY_APP_ID,,true,true,,,,critical,,2024-01-01 00:00:00 UTC,2024-01-01 00:00:00 UTC
+legacy.owasp.org,URL,,false,false,,,,none,,2024-01-01 00:00:00 UTC,2024-01-01 00:00:00 UTC
+192.0.2.128/25,CIDR,,false,false,,,,none,,2024-01-01 00:00:00 UTC,2024-01-01 00:00:00 UTC
+`)
+
+var hackerOneJSON = []byte(`{
+  "data": [
+    {"id": "1", "type": "structured-scope", "attributes": {"asset_type": "WILDCARD", "asset_identifier": "*.owasp.org", "eligible_for_submission": true}},
+    {"id": "2", "type": "structured-scope", "attributes": {"asset_type": "URL", "asset_identifier": "https://app.example.com/login", "eligible_for_submission": true}},
+    {"id": "3", "type": "structured-scope", "attributes": {"asset_type": "CIDR", "asset_identifier": "192.0.2.0/24", "eligible_for_submission": true}},
+    {"id": "4", "type": "structured-scope", "attributes": {"asset_type": "IP_ADDRESS", "asset_identifier": "198.51.100.7", "eligible_for_submission": true}},
+    {"id": "5", "type": "structured-scope", "attributes": {"asset_type": "GOOGLE_PLAY_APP_ID", "asset_identifier": "com.example.app", "eligible_for_submission": true}},
+    {"id": "6", "type": "structured-scope", "attributes": {"asset_type": "URL", "asset_identifier": "legacy.owasp.org", "eligible_for_submission": false}},
+    {"id": "7", "type": "structured-scope", "attributes": {"asset_type": "CIDR", "asset_identifier": "192.0.2.128/25", "eligible_for_submission": false}}
+  ]
+}`)
+
+var bugcrowdJSON = []byte(`{
+  "name": "OWASP",
+  "url": "https://bugcrowd.com/owasp",
+  "targets": {
+    "in_scope": [
+      {"type": "website", "target": "*.owasp.org"},
+      {"type": "api", "target": "https://app.example.com/login"},
+      {"type": "network", "target": "192.0.2.0/24"},
+      {"type": "ip_address", "target": "198.51.100.7"},
+      {"type": "android", "target": "com.example.app"}
+    ],
+    "out_of_scope": [
+      {"type": "website", "target": "legacy.owasp.org"},
+      {"type": "network", "target": "192.0.2.128/25"}
+    ]
+  }
+}`)
+
+var bugcrowdCSV = []byte(`name,category,in_scope
+*.owasp.org,website,true
+https://app.example.com/login,api,true
+192.0.2.0/24,network,true
+198.51.100.7,ip_address,true
+com.example.app,android,true
+legacy.owasp.org,website,false
+192.0.2.128/25,network,false
+`)
+
+func TestImportBugBountyScope(t *testing.T) {
+	want := &Scope{
+		Domains:     []string{"owasp.org"},
+		IP:          []string{"198.51.100.7"},
+		CIDRStrings: []string{"192.0.2.0/24"},
+		Blacklist:   []string{"legacy.owasp.org"},
+		Exclusions:  []string{"192.0.2.128/25"},
+		URLs:        []string{"https://app.example.com:443/login"},
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		importer func(string) (*Scope, []string, error)
+	}{
+		{name: "hackerone csv", data: hackerOneCSV, importer: ImportHackerOneScope},
+		{name: "hackerone json", data: hackerOneJSON, importer: ImportHackerOneScope},
+		{name: "bugcrowd json", data: bugcrowdJSON, importer: ImportBugcrowdScope},
+		{name: "bugcrowd csv", data: bugcrowdCSV, importer: ImportBugcrowdScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, skipped, err := tt.importer(path)
+			if err != nil {
+				t.Fatalf("failed to import the scope: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("imported scope = %+v, want %+v", got, want)
+			}
+			if !reflect.DeepEqual(skipped, []string{"com.example.app"}) {
+				t.Errorf("skipped targets = %v, want [com.example.app]", skipped)
+			}
+		})
+	}
+}
+
+func TestImportBugBountyScopeEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(path, []byte(`{"data": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := ImportHackerOneScope(path); err == nil {
+		t.Errorf("an export without targets did not return an error")
+	}
+	if _, _, err := ImportBugcrowdScope(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Errorf("a missing export file did not return an error")
+	}
+}
+
+func TestImportBugBountyOutOfScope(t *testing.T) {
+	data := []byte(`{
+  "targets": {
+    "in_scope": [
+      {"type": "website", "target": "*.example.com"},
+      {"type": "network", "target": "192.0.2.0/24"},
+      {"type": "api", "target": "https://api.example.org/v1/"},
+      {"type": "api", "target": "api.example.net/v1"},
+      {"type": "website", "target": "https://www.example.net/search?q=1"}
+    ],
+    "out_of_scope": [
+      {"type": "website", "target": "https://example.com/blog"},
+      {"type": "website", "target": "https://shop.example.com/"},
+      {"type": "ip_address", "target": "192.0.2.1-10"},
+      {"type": "ip_address", "target": "192.0.2.200"}
+    ]
+  }
+}`)
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, skipped, err := ImportBugcrowdScope(path)
+	if err != nil {
+		t.Fatalf("failed to import the scope: %v", err)
+	}
+
+	if !reflect.DeepEqual(got.Domains, []string{"example.com"}) {
+		t.Errorf("imported domains = %v, want [example.com]", got.Domains)
+	}
+	if !reflect.DeepEqual(got.Blacklist, []string{"shop.example.com"}) {
+		t.Errorf("imported blacklist = %v, want [shop.example.com]", got.Blacklist)
+	}
+	if len(got.IP) != 0 {
+		t.Errorf("the out of scope range was imported as in scope: %v", got.IP)
+	}
+	excl := []string{"192.0.2.1/32", "192.0.2.10/32", "192.0.2.2/31", "192.0.2.200", "192.0.2.4/30", "192.0.2.8/31"}
+	if !reflect.DeepEqual(got.Exclusions, excl) {
+		t.Errorf("imported exclusions = %v, want %v", got.Exclusions, excl)
+	}
+	if !reflect.DeepEqual(got.URLs, []string{"https://api.example.org:443/v1/"}) {
+		t.Errorf("imported URLs = %v, want [https://api.example.org:443/v1/]", got.URLs)
+	}
+	want := []string{"api.example.net/v1", "https://www.example.net/search?q=1", "https://example.com/blog"}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped targets = %v, want %v", skipped, want)
+	}
+}
+
+func TestTargetDomainName(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "*.owasp.org", want: "owasp.org"},
+		{target: "https://www.owasp.org:8443/path?q=1", want: "www.owasp.org"},
+		{target: "api.owasp.org/v1", want: "api.owasp.org"},
+		{target: "OWASP.org.", want: "owasp.org"},
+		{target: "api-*.owasp.org", want: ""},
+		{target: "localhost", want: ""},
+		{target: "com.example.app store", want: ""},
+	}
+	for _, tt := range tests {
+		if got := targetDomainName(tt.target); got != tt.want {
+			t.Errorf("targetDomainName(%s) = %s, want %s", tt.target, got, tt.want)
+		}
+	}
+}
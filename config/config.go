@@ -16,12 +16,15 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/caffix/stringset"
@@ -43,7 +46,7 @@ type Updater interface {
 
 // Config passes along Amass configuration settings and options.
 type Config struct {
-	sync.Mutex `yaml:"-" json:"-"`
+	sync.RWMutex `yaml:"-" json:"-"`
 
 	// A Universally Unique Identifier (UUID) for the enumeration
 	UUID uuid.UUID `yaml:"-" json:"-"`
@@ -115,7 +118,21 @@ type Config struct {
 	// Determines if zone transfers will be attempted
 	Active bool `yaml:"active,omitempty" json:"active,omitempty"`
 
-	blacklistLock sync.Mutex `yaml:"-" json:"-"`
+	blacklistLock sync.RWMutex `yaml:"-" json:"-"`
+
+	// The reverse-label indexes of the root domains and blacklist, cleared when the lists change
+	domainIndex    atomic.Pointer[labelTrie] `yaml:"-" json:"-"`
+	blacklistIndex atomic.Pointer[labelTrie] `yaml:"-" json:"-"`
+
+	// The transformations with the grouped keys expanded, built when the transformations are loaded
+	resolvedTransforms atomic.Pointer[map[string]*Transformation] `yaml:"-" json:"-"`
+	// The precompiled transformations, rebuilt when the transformations are loaded
+	transformIndex atomic.Pointer[TransformIndex] `yaml:"-" json:"-"`
+
+	// The functions notified of the changes made to the scope
+	scopeSubs     []*scopeSubscriber `yaml:"-" json:"-"`
+	scopeSubsLock sync.Mutex         `yaml:"-" json:"-"`
+	nextScopeSub  uint64             `yaml:"-" json:"-"`
 
 	// A list of data sources that should not be utilized
 	SourceFilter struct {
@@ -144,6 +161,30 @@ type Config struct {
 	// The regular expressions for the root domains added to the enumeration
 	regexps map[string]*regexp.Regexp `yaml:"-" json:"-"`
 
+	// Determines if public suffixes added as root domains are refused or accepted with a warning
+	PublicSuffixRoots string `yaml:"-" json:"-"`
+
+	// Will the registered domain (eTLD+1) of discovered names be treated as a candidate root domain?
+	RegisteredDomainRoots bool `yaml:"-" json:"-"`
+
+	// Will the scope entries be used as the seed when the seed lists no assets?
+	SeedFromScope bool `yaml:"-" json:"-"`
+
+	// Will the seed entries be used as the scope when the scope lists no assets?
+	ScopeFromSeed bool `yaml:"-" json:"-"`
+
+	// Determines if seeds outside the scope are rejected or accepted with a warning
+	SeedOutsideScope string `yaml:"-" json:"-"`
+
+	// Determines if reserved address space in scope is blocked or accepted with a warning
+	ReservedRanges string `yaml:"-" json:"-"`
+
+	// The reserved address space that is permitted in scope without a warning
+	ReservedExceptions []netip.Prefix `yaml:"-" json:"-"`
+
+	// Additional ranges, such as shared hosting and cloud provider networks, checked like reserved address space
+	SharedRanges []netip.Prefix `yaml:"-" json:"-"`
+
 	// Mode should be determined based on scripts utilized
 	Mode string `yaml:"-" json:"-"`
 
@@ -153,21 +194,22 @@ type Config struct {
 	// The Transformations map will contain incoming assets, and what handlers should be called.
 	Transformations map[string]*Transformation `yaml:"transformations" json:"transformations"`
 
+	// Named groups of asset types that can be used on the left side of the transformation keys
+	TransformGroups map[string][]string `yaml:"transform_groups,omitempty" json:"transform_groups,omitempty"`
+
 	// The engine APIURI configuration
 	EngineAPI *EngAPI `yaml:"-" json:"-"`
-
-	// Map to track 'From' types that have a 'none' transformation, indicating no processing should occur.
-	fromWithNone map[string]bool
-
-	// Map to track 'From' types that have at least one valid transformation defined.
-	fromWithValid map[string]bool
 }
 
 // Scope represents the configuration for the enumeration scope.
 type Scope struct {
-	// The root domain names that the enumeration will target
+	// The root domain names that the enumeration will target. Changes made directly to the
+	// list, rather than with the methods of the Config, require a call to Config.ReindexScope
 	Domains []string `yaml:"domains,omitempty" json:"domains,omitempty"`
 
+	// The options restricting the subdomains in scope, keyed by domain name
+	DomainOptions map[string]*DomainOption `yaml:"-" json:"domain_options,omitempty"`
+
 	// IP Net.IP
 	Addresses []net.IP `yaml:"-" json:"ips,omitempty"`
 
@@ -183,11 +225,48 @@ type Scope struct {
 	// CIDR in scope
 	CIDRStrings []string `yaml:"cidrs,omitempty" json:"-"`
 
-	// The ports checked for certificates
-	Ports []int `yaml:"ports,omitempty" json:"ports,omitempty"`
+	// The ports checked for certificates. Port ranges larger than 256 ports are only held in PortRanges
+	Ports []int `yaml:"-" json:"ports,omitempty"`
+
+	// Port numbers, ranges, protocols and named sets in scope
+	PortStrings []string `yaml:"ports,omitempty" json:"port_specs,omitempty"`
 
-	// A blacklist of subdomain names that will not be investigated
+	// The port ranges parsed from the port specifications
+	PortRanges []*PortRange `yaml:"-" json:"-"`
+
+	// A blacklist of subdomain names that will not be investigated. Changes made directly to the
+	// list, rather than with the methods of the Config, require a call to Config.ReindexScope
 	Blacklist []string `yaml:"blacklist,omitempty" json:"blacklist,omitempty"`
+
+	// IP addresses and CIDRs that are out of scope, even when covered by the entries above
+	Exclusions []string `yaml:"exclusions,omitempty" json:"exclusions,omitempty"`
+
+	// The networks parsed from the exclusions
+	ExcludedNets []*net.IPNet `yaml:"-" json:"-"`
+
+	// The organizations in scope, compared without regard to case
+	Organizations []string `yaml:"organizations,omitempty" json:"organizations,omitempty"`
+
+	// The contact email addresses in scope, where @example.com includes every mailbox at the domain
+	Emails []string `yaml:"emails,omitempty" json:"emails,omitempty"`
+
+	// The URLs in scope, where the path is a prefix for the URLs included
+	URLs []string `yaml:"urls,omitempty" json:"urls,omitempty"`
+
+	// The URLs parsed from the URL entries
+	URLPrefixes []*url.URL `yaml:"-" json:"-"`
+
+	// The periods of time when active testing is permitted
+	Windows []*Window `yaml:"windows,omitempty" json:"windows,omitempty"`
+
+	// The ASN entries provided as strings, such as file paths, to be expanded when loaded
+	asnEntries []string
+
+	// The ranges from the ips entries that are too large to be expanded into Addresses
+	hostRanges []addrRange
+
+	// The ips entries with the address ranges parsed from them when the scope was loaded
+	ipEntries []ipEntry
 }
 
 // NewConfig returns a default configuration object.
@@ -209,6 +288,11 @@ func NewConfig() *Config {
 		EditDistance:        1,
 		Recursive:           true,
 		MinimumTTL:          1440,
+		PublicSuffixRoots:   PublicSuffixRefuse,
+		SeedFromScope:       true,
+		ScopeFromSeed:       true,
+		SeedOutsideScope:    SeedOutsideScopeWarn,
+		ReservedRanges:      ReservedRangesWarn,
 		ResolversQPS:        DefaultQueriesPerPublicResolver,
 		TrustedQPS:          DefaultQueriesPerBaselineResolver,
 		DataSrcConfigs: &DataSourceConfig{
@@ -292,6 +376,8 @@ func (c *Config) LoadSettings(path string) error {
 		c.loadTransformSettings,
 		c.loadEngineSettings,
 		c.loadActiveSettings,
+		c.loadPublicSuffixSettings,
+		c.loadReservedRangeSettings,
 	}
 	for _, load := range loads {
 		if err := load(c); err != nil {
@@ -302,6 +388,13 @@ func (c *Config) LoadSettings(path string) error {
 	return nil
 }
 
+// warnf writes a warning about the configuration to the logger, when one has been provided.
+func (c *Config) warnf(format string, v ...interface{}) {
+	if c.Log != nil {
+		c.Log.Printf("warning: "+format, v...)
+	}
+}
+
 // AbsPathFromConfigDir Creates a file path that is relative the the configuration file location.
 // If the path is already absolute, return it as is.
 func (c *Config) AbsPathFromConfigDir(path string) (string, error) {
@@ -377,18 +470,30 @@ func OutputDirectory(dir ...string) string {
 
 // GetListFromFile reads a wordlist text or gzip file and returns the slice of words.
 func GetListFromFile(path string) ([]string, error) {
+	reader, closeFile, err := openListFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	s, err := getWordList(reader)
+	return s, err
+}
+
+// openListFile opens a text or gzip list file and returns the reader for the contents,
+// along with the function that closes the file.
+func openListFile(path string) (io.Reader, func(), error) {
 	var reader io.Reader
 
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %v", err)
+		return nil, nil, fmt.Errorf("failed to get absolute path: %v", err)
 	}
 
 	file, err := os.Open(absPath)
 	if err != nil {
-		return nil, fmt.Errorf("error opening the file %s: %v", absPath, err)
+		return nil, nil, fmt.Errorf("error opening the file %s: %v", absPath, err)
 	}
-	defer file.Close()
 	reader = file
 
 	// We need to determine if this is a gzipped file or a plain text file, so we
@@ -397,24 +502,27 @@ func GetListFromFile(path string) ([]string, error) {
 	// next reader
 	head := make([]byte, 512)
 	if _, err = file.Read(head); err != nil {
-		return nil, fmt.Errorf("error reading the first 512 bytes from %s: %s", absPath, err)
+		file.Close()
+		return nil, nil, fmt.Errorf("error reading the first 512 bytes from %s: %s", absPath, err)
 	}
 	if _, err = file.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("error rewinding the file %s: %s", absPath, err)
+		file.Close()
+		return nil, nil, fmt.Errorf("error rewinding the file %s: %s", absPath, err)
 	}
 
 	// Read the file as gzip if it's actually compressed
 	if mt := http.DetectContentType(head); mt == "application/gzip" || mt == "application/x-gzip" {
 		gzReader, err := gzip.NewReader(file)
 		if err != nil {
-			return nil, fmt.Errorf("error gz-reading the file %s: %v", absPath, err)
+			file.Close()
+			return nil, nil, fmt.Errorf("error gz-reading the file %s: %v", absPath, err)
 		}
-		defer gzReader.Close()
-		reader = gzReader
+		return gzReader, func() {
+			gzReader.Close()
+			file.Close()
+		}, nil
 	}
-
-	s, err := getWordList(reader)
-	return s, err
+	return reader, func() { file.Close() }, nil
 }
 
 func getWordList(reader io.Reader) ([]string, error) {
@@ -34,7 +34,7 @@ func TestDomainRegex(t *testing.T) {
 
 func TestAddDomains(t *testing.T) {
 	c := NewConfig()
-	example := "owasp.org/test"
+	example := "test.owasp.org"
 	list := []string{"owasp.org", "google.com", "yahoo.com"}
 	c.AddDomains(list...)
 	got := c.Domains()
@@ -117,7 +117,8 @@ func (c *Config) loadDataSourceSettings(cfg *Config) error {
 	// Assign the unmarshalled DataSourceConfig to the Config struct
 	c.DataSrcConfigs = &dsConfig
 	c.DataSrcConfigs.ttlCheck()
-	return nil
+	// The index of the transformations holds the TTLs of the data sources
+	return c.reindexTransformations()
 }
 
 func (dsc *DataSourceConfig) ttlCheck() {
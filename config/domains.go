@@ -0,0 +1,245 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/owasp-amass/amass/v4/net/dns"
+	"gopkg.in/yaml.v3"
+)
+
+// The regular expression for a single DNS label followed by a period.
+const labelRE = "([a-zA-Z0-9]{1}|[_a-zA-Z0-9]{1}[_a-zA-Z0-9-]{0,61}[a-zA-Z0-9]{1})[.]{1}"
+
+// DomainOption restricts the names that a domain in scope includes. Without an option,
+// the domain and all of its subdomains are in scope.
+//
+// In the YAML configuration, the domain is provided as a mapping instead of a name:
+//
+//	domains:
+//	  - example.com
+//	  - name: www.example.org
+//	    exact: true
+//	  - name: example.net
+//	    max_depth: 2
+type DomainOption struct {
+	// The domain name that the option applies to
+	Name string `yaml:"name" json:"name"`
+
+	// Only the domain name itself is in scope, and none of its subdomains
+	Exact bool `yaml:"exact,omitempty" json:"exact,omitempty"`
+
+	// The maximum number of labels below the domain name that are in scope
+	MaxDepth int `yaml:"max_depth,omitempty" json:"max_depth,omitempty"`
+}
+
+// UnmarshalYAML accepts the domains in scope as names or as mappings containing a DomainOption,
+// and the ASNs as numbers or as strings, such as file paths, that are expanded later.
+func (s *Scope) UnmarshalYAML(value *yaml.Node) error {
+	type plain Scope
+
+	node, opts, err := extractDomainOptions(value)
+	if err != nil {
+		return err
+	}
+	node, asnEntries := extractASNEntries(node)
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+
+	s.DomainOptions = opts
+	s.asnEntries = asnEntries
+	return nil
+}
+
+// MarshalYAML writes the domains that have a DomainOption as mappings, so the options are preserved.
+// The ports are written as the port specifications, which are built from the port ranges or numbers
+// when the scope was not loaded from a configuration.
+func (s Scope) MarshalYAML() (interface{}, error) {
+	type plain Scope
+
+	s.PortStrings = s.portSpecs()
+	var node yaml.Node
+	if err := node.Encode(plain(s)); err != nil {
+		return nil, err
+	}
+	if len(s.DomainOptions) == 0 {
+		return &node, nil
+	}
+
+	if seq := mappingValue(&node, "domains"); seq != nil {
+		for i, item := range seq.Content {
+			opt, found := s.DomainOptions[normalizeQuery(item.Value)]
+			if !found || item.Kind != yaml.ScalarNode {
+				continue
+			}
+
+			n := &yaml.Node{}
+			o := *opt
+			o.Name = item.Value
+			if err := n.Encode(&o); err != nil {
+				return nil, err
+			}
+			seq.Content[i] = n
+		}
+	}
+	return &node, nil
+}
+
+// extractDomainOptions returns a copy of the scope node with the domain mappings replaced by their
+// names, along with the options found in those mappings.
+func extractDomainOptions(value *yaml.Node) (*yaml.Node, map[string]*DomainOption, error) {
+	seq := mappingValue(value, "domains")
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return value, nil, nil
+	}
+
+	names := &yaml.Node{Kind: yaml.SequenceNode, Tag: seq.Tag, Line: seq.Line, Column: seq.Column}
+	opts := make(map[string]*DomainOption)
+	for _, item := range seq.Content {
+		if item.Kind != yaml.MappingNode {
+			names.Content = append(names.Content, item)
+			continue
+		}
+
+		var opt DomainOption
+		if err := item.Decode(&opt); err != nil {
+			return nil, nil, err
+		}
+		if opt.Name == "" {
+			return nil, nil, fmt.Errorf("line %d: the domain entry is missing the name", item.Line)
+		}
+
+		opts[opt.Name] = &opt
+		names.Content = append(names.Content, &yaml.Node{
+			Kind:   yaml.ScalarNode,
+			Tag:    "!!str",
+			Value:  opt.Name,
+			Line:   item.Line,
+			Column: item.Column,
+		})
+	}
+	if len(opts) == 0 {
+		return value, nil, nil
+	}
+	return replaceMappingValue(value, "domains", names), opts, nil
+}
+
+// extractASNEntries returns a copy of the scope node with only the numbers in the ASNs sequence,
+// along with the string entries that were removed from it.
+func extractASNEntries(value *yaml.Node) (*yaml.Node, []string) {
+	seq := mappingValue(value, "asns")
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return value, nil
+	}
+
+	var entries []string
+	nums := &yaml.Node{Kind: yaml.SequenceNode, Tag: seq.Tag, Line: seq.Line, Column: seq.Column}
+	for _, item := range seq.Content {
+		if item.Kind == yaml.ScalarNode && item.ShortTag() == "!!str" {
+			entries = append(entries, item.Value)
+			continue
+		}
+		nums.Content = append(nums.Content, item)
+	}
+	if len(entries) == 0 {
+		return value, nil
+	}
+	return replaceMappingValue(value, "asns", nums), entries
+}
+
+// replaceMappingValue returns a copy of the YAML mapping with the value for the key replaced.
+func replaceMappingValue(node *yaml.Node, key string, value *yaml.Node) *yaml.Node {
+	n := *node
+	n.Content = make([]*yaml.Node, len(node.Content))
+	copy(n.Content, node.Content)
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content[i+1] = value
+		}
+	}
+	return &n
+}
+
+// mappingValue returns the value node for the key in the YAML mapping, or nil when it is not found.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// populateDomainOptions validates the domain options and keys them by the normalized domain names.
+func (s *Scope) populateDomainOptions() error {
+	if len(s.DomainOptions) == 0 {
+		return nil
+	}
+
+	opts := make(map[string]*DomainOption, len(s.DomainOptions))
+	for name, opt := range s.DomainOptions {
+		if opt == nil {
+			continue
+		}
+
+		n, err := NormalizeDomain(name)
+		if err != nil {
+			return fmt.Errorf("invalid domain option: %v", err)
+		}
+		if opt.MaxDepth < 0 {
+			return fmt.Errorf("the max_depth for %s cannot be negative", n)
+		}
+		if opt.Exact && opt.MaxDepth > 0 {
+			return fmt.Errorf("the domain %s cannot be both exact and limited by max_depth", n)
+		}
+
+		o := *opt
+		o.Name = n
+		opts[n] = &o
+	}
+
+	s.DomainOptions = opts
+	return nil
+}
+
+// allowsName returns true if the domain options permit the name, which must already be
+// known to end with the domain, to be in scope.
+func (s *Scope) allowsName(name, domain string) bool {
+	opt, found := s.DomainOptions[domain]
+	if !found {
+		return true
+	}
+
+	depth := strings.Count(name, ".") - strings.Count(domain, ".")
+	if opt.Exact {
+		return depth == 0
+	}
+	return opt.MaxDepth == 0 || depth <= opt.MaxDepth
+}
+
+// domainRegex returns the regular expression that matches the names in scope for the domain.
+// The expressions for exact and depth-limited domains are anchored, since a partial match within
+// a longer name could not respect the restriction.
+func (s *Scope) domainRegex(domain string) *regexp.Regexp {
+	opt, found := s.DomainOptions[domain]
+	if !found || (!opt.Exact && opt.MaxDepth == 0) {
+		return dns.SubdomainRegex(domain)
+	}
+
+	if opt.Exact {
+		return regexp.MustCompile("^" + regexp.QuoteMeta(domain) + "$")
+	}
+	return regexp.MustCompile("^(" + labelRE + "){0," + strconv.Itoa(opt.MaxDepth) + "}" + regexp.QuoteMeta(domain) + "$")
+}
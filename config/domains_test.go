@@ -0,0 +1,170 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const domainOptionsYAML = `
+scope:
+  domains:
+    - owasp.org
+    - name: WWW.Example.com
+      exact: true
+    - name: example.net
+      max_depth: 2
+`
+
+func loadDomainOptions(t *testing.T, data string) *Config {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(data), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+	domains := c.Scope.Domains
+	c.Scope.Domains = nil
+	if err := c.AddDomainsE(domains...); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestDomainOptionsUnmarshal(t *testing.T) {
+	c := loadDomainOptions(t, domainOptionsYAML)
+
+	if got, want := sortedSet(c.Scope.Domains), []string{"example.net", "owasp.org", "www.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Domains = %v, want %v", got, want)
+	}
+
+	want := map[string]*DomainOption{
+		"www.example.com": {Name: "www.example.com", Exact: true},
+		"example.net":     {Name: "example.net", MaxDepth: 2},
+	}
+	if !reflect.DeepEqual(c.Scope.DomainOptions, want) {
+		t.Errorf("DomainOptions = %v, want %v", c.Scope.DomainOptions, want)
+	}
+}
+
+func TestDomainOptionsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "missing name",
+			data: "scope:\n  domains:\n    - exact: true\n",
+		},
+		{
+			name: "exact with max depth",
+			data: "scope:\n  domains:\n    - name: example.com\n      exact: true\n      max_depth: 1\n",
+		},
+		{
+			name: "negative max depth",
+			data: "scope:\n  domains:\n    - name: example.com\n      max_depth: -1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			err := yaml.Unmarshal([]byte(tt.data), c)
+			if err == nil {
+				err = c.loadSeedandScopeSettings()
+			}
+			if err == nil {
+				t.Errorf("expected an error for the invalid domain option")
+			}
+		})
+	}
+}
+
+func TestDomainOptionsMatching(t *testing.T) {
+	c := loadDomainOptions(t, domainOptionsYAML)
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "unrestricted root",
+			value: "a.b.c.owasp.org",
+			want:  "owasp.org",
+		},
+		{
+			name:  "exact domain itself",
+			value: "www.example.com",
+			want:  "www.example.com",
+		},
+		{
+			name:  "subdomain of an exact domain",
+			value: "dev.www.example.com",
+		},
+		{
+			name:  "parent of an exact domain",
+			value: "example.com",
+		},
+		{
+			name:  "depth limited root",
+			value: "example.net",
+			want:  "example.net",
+		},
+		{
+			name:  "within the max depth",
+			value: "a.b.example.net",
+			want:  "example.net",
+		},
+		{
+			name:  "beyond the max depth",
+			value: "a.b.c.example.net",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.WhichDomain(tt.value); got != tt.want {
+				t.Errorf("WhichDomain() = %s, want %s", got, tt.want)
+			}
+			if got := c.IsDomainInScope(tt.value); got != (tt.want != "") {
+				t.Errorf("IsDomainInScope() = %v, want %v", got, tt.want != "")
+			}
+			if tt.want == "" {
+				return
+			}
+			if re := c.DomainRegex(tt.want); re == nil || !re.MatchString(tt.value) {
+				t.Errorf("DomainRegex() did not match %s", tt.value)
+			}
+		})
+	}
+
+	if re := c.DomainRegex("www.example.com"); re.MatchString("dev.www.example.com") {
+		t.Errorf("DomainRegex() matched a subdomain of an exact domain")
+	}
+	if re := c.DomainRegex("example.net"); re.MatchString("a.b.c.example.net") {
+		t.Errorf("DomainRegex() matched a name beyond the max depth")
+	}
+}
+
+func TestDomainOptionsMarshal(t *testing.T) {
+	c := loadDomainOptions(t, domainOptionsYAML)
+
+	out, err := yaml.Marshal(&Config{Scope: c.Scope})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "exact: true") || !strings.Contains(string(out), "max_depth: 2") {
+		t.Errorf("MarshalYAML() did not preserve the domain options:\n%s", out)
+	}
+
+	rt := loadDomainOptions(t, string(out))
+	if !reflect.DeepEqual(rt.Scope.DomainOptions, c.Scope.DomainOptions) {
+		t.Errorf("DomainOptions after the round trip = %v, want %v", rt.Scope.DomainOptions, c.Scope.DomainOptions)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"net/netip"
+	neturl "net/url"
+	"strconv"
+	"strings"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/contact"
+	"github.com/owasp-amass/open-asset-model/url"
+)
+
+// Explain checks the DNS name, IP address, CIDR, ASN (e.g. AS26808), URL or email address provided
+// against the scope and returns the verdict, including the scope entry that matched, such as the root
+// domain, CIDR, address range or blacklist entry, or the reason that nothing matched.
+func (c *Config) Explain(value string) *ScopeVerdict {
+	v := strings.TrimSpace(value)
+
+	if strings.Contains(v, "://") {
+		return c.urlVerdict(&url.URL{Raw: v, Host: urlHostname(v)})
+	}
+	if strings.Contains(v, "@") {
+		return c.emailVerdict(&contact.EmailAddress{Address: v})
+	}
+
+	if addr, err := netip.ParseAddr(v); err == nil {
+		return c.addrVerdict(oam.IPAddress, addr.Unmap())
+	}
+	if prefix, err := netip.ParsePrefix(v); err == nil {
+		return c.netblockVerdict(oam.Netblock, prefix.Masked())
+	}
+	if num, found := strings.CutPrefix(strings.ToUpper(v), "AS"); found {
+		if asn, err := strconv.Atoi(num); err == nil {
+			return c.asnVerdict(oam.AutonomousSystem, asn)
+		}
+	}
+	return c.fqdnVerdict(oam.FQDN, v)
+}
+
+func urlHostname(rawURL string) string {
+	if u, err := neturl.Parse(rawURL); err == nil {
+		return u.Hostname()
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestExplain(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - owasp.org
+  ips:
+    - 192.168.1.5
+    - 192.168.2.10-20
+  cidrs:
+    - 10.0.0.0/16
+  asns:
+    - 26808
+  blacklist:
+    - internal.owasp.org
+  exclusions:
+    - 10.0.5.0/24`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+	c.AddDomains(c.Scope.Domains...)
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+		match string
+		rule  string
+	}{
+		{
+			name:  "subdomain of a root domain",
+			value: "www.owasp.org",
+			want:  true,
+			match: "owasp.org",
+			rule:  "domains",
+		},
+		{
+			name:  "blacklisted subdomain",
+			value: "vpn.internal.owasp.org",
+			match: "internal.owasp.org",
+			rule:  "blacklist",
+		},
+		{
+			name:  "name outside the scope",
+			value: "www.example.com",
+		},
+		{
+			name:  "address within a cidr",
+			value: "10.0.1.1",
+			want:  true,
+			match: "10.0.0.0/16",
+			rule:  "cidrs",
+		},
+		{
+			name:  "address within a range entry",
+			value: " 192.168.2.15 ",
+			want:  true,
+			match: "192.168.2.10-20",
+			rule:  "ips",
+		},
+		{
+			name:  "single address entry",
+			value: "192.168.1.5",
+			want:  true,
+			match: "192.168.1.5",
+			rule:  "ips",
+		},
+		{
+			name:  "excluded address",
+			value: "10.0.5.9",
+			match: "10.0.5.0/24",
+			rule:  "exclusions",
+		},
+		{
+			name:  "address outside the scope",
+			value: "172.16.0.1",
+		},
+		{
+			name:  "cidr within the scope",
+			value: "10.0.8.0/24",
+			want:  true,
+			match: "10.0.0.0/16",
+			rule:  "cidrs",
+		},
+		{
+			name:  "autonomous system",
+			value: "as26808",
+			want:  true,
+			match: "26808",
+			rule:  "asns",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.Explain(tt.value)
+			if v.InScope != tt.want {
+				t.Errorf("Explain() = %v, want %v: %s", v.InScope, tt.want, v.Reason)
+			}
+			if v.Match != tt.match {
+				t.Errorf("Explain() match = %s, want %s", v.Match, tt.match)
+			}
+			if v.Rule != tt.rule {
+				t.Errorf("Explain() rule = %s, want %s", v.Rule, tt.rule)
+			}
+			if v.Reason == "" {
+				t.Errorf("Explain() did not provide a reason")
+			}
+		})
+	}
+}
+
+func TestExplainWithoutScope(t *testing.T) {
+	c := NewConfig()
+	c.Scope = nil
+
+	for _, value := range []string{"x@example.com", "www.example.com", "https://www.example.com/", "192.0.2.1"} {
+		if v := c.Explain(value); v.InScope {
+			t.Errorf("Explain(%s) = in scope without a scope", value)
+		}
+	}
+	if got := c.WhichDomain("www.example.com"); got != "" {
+		t.Errorf("WhichDomain() = %s without a scope", got)
+	}
+	if c.Blacklisted("www.example.com") {
+		t.Errorf("Blacklisted() = true without a scope")
+	}
+}
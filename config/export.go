@@ -0,0 +1,90 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NmapTargets returns the addresses and CIDRs in scope as an nmap target list (-iL).
+// Adjacent and overlapping entries are merged and written as the fewest CIDRs that cover them.
+func (s *Scope) NmapTargets() []string {
+	return nmapEntries(s.addressRanges())
+}
+
+// NmapExcludes returns the scope exclusions as an nmap exclude file (--excludefile).
+func (s *Scope) NmapExcludes() []string {
+	return nmapEntries(networkRanges(s.ExcludedNets, nil))
+}
+
+// MasscanRanges returns the addresses and CIDRs in scope as a masscan include file (-iL).
+// Adjacent and overlapping entries are merged and written as CIDRs or start-end ranges.
+func (s *Scope) MasscanRanges() []string {
+	return masscanEntries(s.addressRanges())
+}
+
+// MasscanExcludes returns the scope exclusions as a masscan exclude file (--excludefile).
+func (s *Scope) MasscanExcludes() []string {
+	return masscanEntries(networkRanges(s.ExcludedNets, nil))
+}
+
+// DomainList returns the root domain names in scope, one per entry, as expected by tools like nuclei.
+func (s *Scope) DomainList() []string {
+	var domains []string
+
+	for _, d := range s.Domains {
+		if n := strings.Trim(strings.ToLower(strings.TrimSpace(d)), "."); n != "" {
+			domains = append(domains, n)
+		}
+	}
+	return sortedSet(domains)
+}
+
+// WriteList writes the entries provided to the writer, one entry per line.
+func WriteList(w io.Writer, entries []string) error {
+	bw := bufio.NewWriter(w)
+
+	for _, e := range entries {
+		if _, err := fmt.Fprintln(bw, e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func nmapEntries(ranges []addrRange) []string {
+	var entries []string
+
+	for _, r := range ranges {
+		if r.isSingle() {
+			entries = append(entries, r.start.String())
+			continue
+		}
+		for _, p := range r.prefixes() {
+			if p.Bits() == p.Addr().BitLen() {
+				entries = append(entries, p.Addr().String())
+			} else {
+				entries = append(entries, p.String())
+			}
+		}
+	}
+	return entries
+}
+
+func masscanEntries(ranges []addrRange) []string {
+	var entries []string
+
+	for _, r := range ranges {
+		if p := r.prefixes(); len(p) == 1 && !r.isSingle() {
+			entries = append(entries, p[0].String())
+			continue
+		}
+		entries = append(entries, r.String())
+	}
+	return entries
+}
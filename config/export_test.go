@@ -0,0 +1,87 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func exportTestScope(t *testing.T) *Scope {
+	s := &Scope{
+		Domains:     []string{"OWASP.org", "example.com.", "owasp.org"},
+		IP:          []string{"192.168.0.3-8", "192.168.0.10-192.168.0.20", "10.0.0.7", "2001:db8::1"},
+		CIDRStrings: []string{"192.0.2.0/24", "192.0.2.128/25", "198.51.100.0/25", "198.51.100.128/25"},
+		Exclusions:  []string{"192.0.2.64/26", "192.0.2.5"},
+	}
+	s.CIDRs = s.toCIDRs(s.CIDRStrings)
+	if err := s.populate(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNmapTargets(t *testing.T) {
+	s := exportTestScope(t)
+
+	want := []string{
+		"10.0.0.7",
+		"192.0.2.0/24",
+		"192.168.0.3",
+		"192.168.0.4/30",
+		"192.168.0.8",
+		"192.168.0.10/31",
+		"192.168.0.12/30",
+		"192.168.0.16/30",
+		"192.168.0.20",
+		"198.51.100.0/24",
+		"2001:db8::1",
+	}
+	if got := s.NmapTargets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NmapTargets() = %v, want %v", got, want)
+	}
+	if got := s.NmapExcludes(); !reflect.DeepEqual(got, []string{"192.0.2.5", "192.0.2.64/26"}) {
+		t.Errorf("NmapExcludes() = %v", got)
+	}
+}
+
+func TestMasscanRanges(t *testing.T) {
+	s := exportTestScope(t)
+
+	want := []string{
+		"10.0.0.7",
+		"192.0.2.0/24",
+		"192.168.0.3-192.168.0.8",
+		"192.168.0.10-192.168.0.20",
+		"198.51.100.0/24",
+		"2001:db8::1",
+	}
+	if got := s.MasscanRanges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MasscanRanges() = %v, want %v", got, want)
+	}
+	if got := s.MasscanExcludes(); !reflect.DeepEqual(got, []string{"192.0.2.5", "192.0.2.64/26"}) {
+		t.Errorf("MasscanExcludes() = %v", got)
+	}
+}
+
+func TestDomainList(t *testing.T) {
+	s := exportTestScope(t)
+
+	if got := s.DomainList(); !reflect.DeepEqual(got, []string{"example.com", "owasp.org"}) {
+		t.Errorf("DomainList() = %v", got)
+	}
+}
+
+func TestWriteList(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := WriteList(&buf, []string{"192.0.2.0/24", "10.0.0.7"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "192.0.2.0/24\n10.0.0.7\n" {
+		t.Errorf("WriteList() wrote %q", got)
+	}
+}
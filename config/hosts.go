@@ -0,0 +1,39 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"iter"
+	"net/netip"
+)
+
+// maxExpandedRange is the largest number of addresses in an ips entry that is expanded into Addresses.
+const maxExpandedRange = 256
+
+// Hosts returns an iterator over the addresses of the loaded Addresses, CIDRs and ips entries, without
+// the ExcludedNets. Each address is produced once and in order. The ranges are walked lazily, so a range
+// as large as a /8 network does not need to be expanded in memory.
+func (s *Scope) Hosts() iter.Seq[netip.Addr] {
+	return func(yield func(netip.Addr) bool) {
+		if s == nil {
+			return
+		}
+
+		excluded := networkRanges(s.ExcludedNets, nil)
+		for _, r := range subtractRanges(s.addressRanges(), excluded) {
+			for addr := r.start; addr.IsValid() && addr.Compare(r.end) <= 0; addr = addr.Next() {
+				if !yield(addr) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// addressRanges returns the merged ranges covered by the CIDRs, Addresses and the ips entries that
+// were not expanded into Addresses.
+func (s *Scope) addressRanges() []addrRange {
+	return mergeRanges(append(networkRanges(s.CIDRs, s.Addresses), s.hostRanges...))
+}
@@ -0,0 +1,155 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestHosts(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope *Scope
+		want  []string
+	}{
+		{
+			name:  "ranges, CIDRs and addresses in order",
+			scope: &Scope{IP: []string{"192.0.2.10-12", "192.0.2.1"}, CIDRStrings: []string{"198.51.100.0/31"}},
+			want:  []string{"192.0.2.1", "192.0.2.10", "192.0.2.11", "192.0.2.12", "198.51.100.0", "198.51.100.1"},
+		},
+		{
+			name:  "overlapping entries are produced once",
+			scope: &Scope{IP: []string{"192.0.2.0-192.0.2.2", "192.0.2.1"}, CIDRStrings: []string{"192.0.2.2/31"}},
+			want:  []string{"192.0.2.0", "192.0.2.1", "192.0.2.2", "192.0.2.3"},
+		},
+		{
+			name:  "exclusions are skipped",
+			scope: &Scope{CIDRStrings: []string{"192.0.2.0/30"}, Exclusions: []string{"192.0.2.1"}},
+			want:  []string{"192.0.2.0", "192.0.2.2", "192.0.2.3"},
+		},
+		{
+			name:  "ipv6",
+			scope: &Scope{IP: []string{"2001:db8::fffe-2001:db8::1:1"}},
+			want:  []string{"2001:db8::fffe", "2001:db8::ffff", "2001:db8::1:0", "2001:db8::1:1"},
+		},
+		{
+			name:  "no networks",
+			scope: &Scope{Domains: []string{"owasp.org"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.scope.populate(); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for addr := range tt.scope.Hosts() {
+				got = append(got, addr.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostsLoadedNetworks(t *testing.T) {
+	_, cidr, _ := net.ParseCIDR("198.51.100.0/30")
+	_, excl, _ := net.ParseCIDR("198.51.100.2/32")
+	scope := &Scope{
+		// The string sections are not parsed again
+		IP:           []string{"203.0.113.1"},
+		Addresses:    []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("198.51.100.1")},
+		CIDRs:        []*net.IPNet{cidr},
+		ExcludedNets: []*net.IPNet{excl},
+	}
+
+	var got []string
+	for addr := range scope.Hosts() {
+		got = append(got, addr.String())
+	}
+	if want := []string{"192.0.2.1", "198.51.100.0", "198.51.100.1", "198.51.100.3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hosts() = %v, want %v", got, want)
+	}
+}
+
+func TestHostsLargeRange(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+seed:
+  ips:
+    - 10.0.0.0-10.255.255.255
+    - 192.0.2.1-3
+scope:
+  cidrs:
+    - 10.0.0.0/8
+    - 192.0.2.0/24`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the small range is expanded into the addresses
+	if len(c.Seed.Addresses) != 3 {
+		t.Errorf("the seed contains %d addresses, want 3", len(c.Seed.Addresses))
+	}
+
+	var count int
+	var last netip.Addr
+	for addr := range c.Seed.Hosts() {
+		if count++; count == 1000 {
+			last = addr
+			break
+		}
+	}
+	if want := netip.MustParseAddr("10.0.3.231"); last != want {
+		t.Errorf("the 1000th host = %s, want %s", last, want)
+	}
+
+	scope := &Scope{IP: []string{"10.0.0.0-10.255.255.255"}}
+	if err := scope.populate(); err != nil {
+		t.Fatal(err)
+	}
+	c.Scope = scope
+	if match, rule := c.whichNetwork(netip.MustParseAddr("10.200.1.1").AsSlice()); match != "10.0.0.0-10.255.255.255" || rule != "ips" {
+		t.Errorf("whichNetwork() = %s, %s, want the large range", match, rule)
+	}
+	if v := c.Explain("10.128.0.0/16"); !v.InScope || v.Match != "10.0.0.0-10.255.255.255" {
+		t.Errorf("Explain() = %v with match %s, want the large range", v.InScope, v.Match)
+	}
+	if got := scope.NmapTargets(); !reflect.DeepEqual(got, []string{"10.0.0.0/8"}) {
+		t.Errorf("NmapTargets() = %v, want [10.0.0.0/8]", got)
+	}
+}
+
+func TestAddrRangeSizeAtMost(t *testing.T) {
+	tests := []struct {
+		r    string
+		n    uint64
+		want bool
+	}{
+		{r: "192.0.2.1", n: 1, want: true},
+		{r: "192.0.2.0-255", n: 256, want: true},
+		{r: "192.0.2.0-192.0.3.0", n: 256, want: false},
+		{r: "2001:db8::-2001:db8::ff", n: 256, want: true},
+		{r: "2001:db8::-2001:db9::", n: 256, want: false},
+	}
+	for _, tt := range tests {
+		r, err := parseAddrRange(tt.r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.sizeAtMost(tt.n); got != tt.want {
+			t.Errorf("sizeAtMost(%s, %d) = %v, want %v", tt.r, tt.n, got, tt.want)
+		}
+	}
+}
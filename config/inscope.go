@@ -0,0 +1,444 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/certificate"
+	"github.com/owasp-amass/open-asset-model/contact"
+	"github.com/owasp-amass/open-asset-model/domain"
+	"github.com/owasp-amass/open-asset-model/network"
+	"github.com/owasp-amass/open-asset-model/org"
+	"github.com/owasp-amass/open-asset-model/registration"
+	"github.com/owasp-amass/open-asset-model/url"
+)
+
+// ScopeVerdict represents the outcome of checking an Open Asset Model asset against the scope.
+type ScopeVerdict struct {
+	// The type of the asset that was checked
+	AssetType oam.AssetType `json:"asset_type"`
+
+	// The value extracted from the asset and compared against the scope
+	Value string `json:"value"`
+
+	// Is the asset in scope?
+	InScope bool `json:"in_scope"`
+
+	// Does the asset, such as a netblock, only partially overlap with the scope? The asset is not in scope
+	Partial bool `json:"partial,omitempty"`
+
+	// The scope entry that matched the asset, such as a root domain, CIDR, address range or ASN
+	Match string `json:"match,omitempty"`
+
+	// The scope section containing the matched entry, such as domains, cidrs, ips or blacklist
+	Rule string `json:"rule,omitempty"`
+
+	// Describes why the asset is in or out of scope
+	Reason string `json:"reason"`
+}
+
+// InScope checks the asset provided against the scope and returns a verdict describing the outcome.
+// Socket addresses and network endpoints must also have their port in scope.
+func (c *Config) InScope(asset oam.Asset) *ScopeVerdict {
+	if asset == nil {
+		return &ScopeVerdict{Reason: "the asset is nil"}
+	}
+
+	switch v := asset.(type) {
+	case *domain.FQDN:
+		return c.fqdnVerdict(oam.FQDN, v.Name)
+	case domain.FQDN:
+		return c.fqdnVerdict(oam.FQDN, v.Name)
+	case *network.IPAddress:
+		return c.addrVerdict(oam.IPAddress, v.Address)
+	case network.IPAddress:
+		return c.addrVerdict(oam.IPAddress, v.Address)
+	case *network.Netblock:
+		return c.netblockVerdict(oam.Netblock, v.CIDR)
+	case network.Netblock:
+		return c.netblockVerdict(oam.Netblock, v.CIDR)
+	case *network.AutonomousSystem:
+		return c.asnVerdict(oam.AutonomousSystem, v.Number)
+	case network.AutonomousSystem:
+		return c.asnVerdict(oam.AutonomousSystem, v.Number)
+	case *network.SocketAddress:
+		return c.socketVerdict(v)
+	case network.SocketAddress:
+		return c.socketVerdict(&v)
+	case *domain.NetworkEndpoint:
+		return c.endpointVerdict(v)
+	case domain.NetworkEndpoint:
+		return c.endpointVerdict(&v)
+	case *url.URL:
+		return c.urlVerdict(v)
+	case url.URL:
+		return c.urlVerdict(&v)
+	case *contact.EmailAddress:
+		return c.emailVerdict(v)
+	case contact.EmailAddress:
+		return c.emailVerdict(&v)
+	case *org.Organization:
+		return c.orgVerdict(v)
+	case org.Organization:
+		return c.orgVerdict(&v)
+	case *registration.DomainRecord:
+		return c.fqdnVerdict(oam.DomainRecord, v.Domain)
+	case registration.DomainRecord:
+		return c.fqdnVerdict(oam.DomainRecord, v.Domain)
+	case *registration.IPNetRecord:
+		return c.netblockVerdict(oam.IPNetRecord, v.CIDR)
+	case registration.IPNetRecord:
+		return c.netblockVerdict(oam.IPNetRecord, v.CIDR)
+	case *registration.AutnumRecord:
+		return c.asnVerdict(oam.AutnumRecord, v.Number)
+	case registration.AutnumRecord:
+		return c.asnVerdict(oam.AutnumRecord, v.Number)
+	case *certificate.TLSCertificate:
+		return c.fqdnVerdict(oam.TLSCertificate, v.SubjectCommonName)
+	case certificate.TLSCertificate:
+		return c.fqdnVerdict(oam.TLSCertificate, v.SubjectCommonName)
+	}
+
+	return &ScopeVerdict{
+		AssetType: asset.AssetType(),
+		Value:     asset.Key(),
+		Reason:    fmt.Sprintf("scope checks are not supported for the %s asset type", asset.AssetType()),
+	}
+}
+
+func (c *Config) fqdnVerdict(atype oam.AssetType, name string) *ScopeVerdict {
+	n := strings.ToLower(strings.TrimSpace(name))
+	v := &ScopeVerdict{AssetType: atype, Value: n}
+
+	if n == "" {
+		v.Reason = "the asset does not contain a DNS name"
+		return v
+	}
+	// Wildcard names in certificates are checked using the base name
+	n = strings.TrimPrefix(n, "*.")
+
+	if bl := c.whichBlacklisted(n); bl != "" {
+		v.Match = bl
+		v.Rule = "blacklist"
+		v.Reason = fmt.Sprintf("%s is blacklisted by %s", n, bl)
+		return v
+	}
+	if d := c.WhichDomain(n); d != "" {
+		v.InScope = true
+		v.Match = d
+		v.Rule = "domains"
+		v.Reason = fmt.Sprintf("%s is a subdomain of %s", n, d)
+		return v
+	}
+	// DNS names can also be IP addresses, as found in URLs and certificates
+	if addr, err := netip.ParseAddr(n); err == nil {
+		return c.addrVerdict(atype, addr)
+	}
+
+	v.Reason = fmt.Sprintf("%s does not match any domain in scope", n)
+	return v
+}
+
+func (c *Config) addrVerdict(atype oam.AssetType, addr netip.Addr) *ScopeVerdict {
+	v := &ScopeVerdict{AssetType: atype, Value: addr.String()}
+
+	if !addr.IsValid() {
+		v.Reason = "the asset does not contain a valid IP address"
+		return v
+	}
+	if excl := c.whichExclusion(net.IP(addr.Unmap().AsSlice())); excl != "" {
+		v.Match = excl
+		v.Rule = "exclusions"
+		v.Reason = fmt.Sprintf("%s is excluded by %s", v.Value, excl)
+		return v
+	}
+	if match, rule := c.whichNetwork(net.IP(addr.Unmap().AsSlice())); match != "" {
+		v.InScope = true
+		v.Match = match
+		v.Rule = rule
+		v.Reason = fmt.Sprintf("%s is within %s", v.Value, match)
+		return v
+	}
+
+	v.Reason = fmt.Sprintf("%s does not match any address or CIDR in scope", v.Value)
+	return v
+}
+
+// netblockVerdict finds the netblock in scope when it is contained within a CIDR or an address range in
+// scope and does not overlap with an exclusion. A netblock that only overlaps with the scope is reported as
+// a partial overlap and is not in scope, so that callers do not enumerate the addresses outside the scope.
+func (c *Config) netblockVerdict(atype oam.AssetType, prefix netip.Prefix) *ScopeVerdict {
+	v := &ScopeVerdict{AssetType: atype, Value: prefix.String()}
+
+	if !prefix.IsValid() {
+		v.Reason = "the asset does not contain a valid CIDR"
+		return v
+	}
+
+	addr, bits := prefix.Addr(), prefix.Bits()
+	if addr.Is4In6() {
+		// A shorter prefix also covers addresses outside of the IPv4-mapped range
+		if bits < 96 {
+			v.Reason = fmt.Sprintf("%s is an IPv4-mapped prefix shorter than /96", v.Value)
+			return v
+		}
+		addr, bits = addr.Unmap(), bits-96
+	}
+	pr := prefixRange(netip.PrefixFrom(addr, bits))
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.Scope == nil {
+		v.Reason = fmt.Sprintf("%s does not overlap with any address or CIDR in scope", v.Value)
+		return v
+	}
+
+	var excluded string
+	for _, excl := range c.Scope.ExcludedNets {
+		p, ok := ipNetToPrefix(excl)
+		if !ok {
+			continue
+		}
+		if er := prefixRange(p); er.covers(pr) {
+			v.Match = excl.String()
+			v.Rule = "exclusions"
+			v.Reason = fmt.Sprintf("%s is excluded by %s", v.Value, excl.String())
+			return v
+		} else if er.overlaps(pr) && excluded == "" {
+			excluded = excl.String()
+		}
+	}
+
+	var match, rule string
+	var contained bool
+	check := func(r addrRange, entry, section string) {
+		if contained || !r.overlaps(pr) {
+			return
+		}
+		if r.covers(pr) {
+			match, rule, contained = entry, section, true
+		} else if match == "" {
+			match, rule = entry, section
+		}
+	}
+	for _, cidr := range c.Scope.CIDRs {
+		if p, ok := ipNetToPrefix(cidr); ok {
+			check(prefixRange(p), cidr.String(), "cidrs")
+		}
+	}
+	// The addresses are merged, as the small ips entries are expanded into separate addresses
+	for _, r := range append(networkRanges(nil, c.Scope.Addresses), c.Scope.hostRanges...) {
+		check(r, c.Scope.addrEntry(net.IP(r.start.AsSlice())), "ips")
+	}
+
+	switch {
+	case contained && excluded != "":
+		v.Partial = true
+		v.Match = excluded
+		v.Rule = "exclusions"
+		v.Reason = fmt.Sprintf("%s is within %s, but overlaps with the exclusion %s", v.Value, match, excluded)
+	case contained:
+		v.InScope = true
+		v.Match = match
+		v.Rule = rule
+		v.Reason = fmt.Sprintf("%s is within %s", v.Value, match)
+	case match != "":
+		v.Partial = true
+		v.Match = match
+		v.Rule = rule
+		v.Reason = fmt.Sprintf("%s only partially overlaps with %s", v.Value, match)
+	default:
+		v.Reason = fmt.Sprintf("%s does not overlap with any address or CIDR in scope", v.Value)
+	}
+	return v
+}
+
+func (c *Config) asnVerdict(atype oam.AssetType, asn int) *ScopeVerdict {
+	v := &ScopeVerdict{AssetType: atype, Value: strconv.Itoa(asn)}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.Scope != nil {
+		for _, a := range c.Scope.ASNs {
+			if a == asn {
+				v.InScope = true
+				v.Match = strconv.Itoa(a)
+				v.Rule = "asns"
+				v.Reason = fmt.Sprintf("AS%d is in scope", asn)
+				return v
+			}
+		}
+	}
+
+	v.Reason = fmt.Sprintf("AS%d does not match any ASN in scope", asn)
+	return v
+}
+
+func (c *Config) socketVerdict(sa *network.SocketAddress) *ScopeVerdict {
+	addr := sa.IPAddress
+	if !addr.IsValid() {
+		addr = sa.Address.Addr()
+	}
+	port := sa.Port
+	if port == 0 {
+		port = int(sa.Address.Port())
+	}
+
+	v := c.addrVerdict(oam.SocketAddress, addr)
+	v.Value = sa.Key()
+	return c.portVerdict(v, sa.Protocol, port)
+}
+
+func (c *Config) endpointVerdict(ne *domain.NetworkEndpoint) *ScopeVerdict {
+	v := c.fqdnVerdict(oam.NetworkEndpoint, ne.Name)
+	v.Value = ne.Key()
+	return c.portVerdict(v, ne.Protocol, ne.Port)
+}
+
+// portVerdict takes the verdict for the host portion of an asset and checks the port.
+func (c *Config) portVerdict(v *ScopeVerdict, proto string, port int) *ScopeVerdict {
+	if !v.InScope || port == 0 {
+		return v
+	}
+
+	if !c.IsPortInScope(proto, port) {
+		v.InScope = false
+		v.Reason = fmt.Sprintf("port %d is not in scope", port)
+	}
+	return v
+}
+
+func (c *Config) urlVerdict(u *url.URL) *ScopeVerdict {
+	if match := c.WhichURL(u.Raw); match != "" {
+		return &ScopeVerdict{
+			AssetType: oam.URL,
+			Value:     u.Key(),
+			InScope:   true,
+			Match:     match,
+			Rule:      "urls",
+			Reason:    fmt.Sprintf("%s is within %s", u.Key(), match),
+		}
+	}
+
+	v := c.fqdnVerdict(oam.URL, u.Host)
+	v.Value = u.Key()
+	return v
+}
+
+func (c *Config) emailVerdict(e *contact.EmailAddress) *ScopeVerdict {
+	if match := c.WhichEmail(e.Address); match != "" {
+		return &ScopeVerdict{
+			AssetType: oam.EmailAddress,
+			Value:     e.Key(),
+			InScope:   true,
+			Match:     match,
+			Rule:      "emails",
+			Reason:    fmt.Sprintf("%s matches %s", e.Key(), match),
+		}
+	}
+
+	d := e.Domain
+	if d == "" {
+		if _, after, found := strings.Cut(e.Address, "@"); found {
+			d = after
+		}
+	}
+
+	v := c.fqdnVerdict(oam.EmailAddress, d)
+	v.Value = e.Key()
+	return v
+}
+
+func (c *Config) orgVerdict(o *org.Organization) *ScopeVerdict {
+	v := &ScopeVerdict{AssetType: oam.Organization, Value: o.Key()}
+
+	if match := c.WhichOrganization(o.Name); match != "" {
+		v.InScope = true
+		v.Match = match
+		v.Rule = "organizations"
+		v.Reason = fmt.Sprintf("%s matches the organization %s", o.Name, match)
+		return v
+	}
+
+	v.Reason = fmt.Sprintf("%s does not match any organization in scope", o.Name)
+	return v
+}
+
+// whichNetwork returns the CIDR or address entry in scope that the IP address in the parameter matches,
+// along with the scope section containing it. Addresses covered by the scope exclusions never match.
+func (c *Config) whichNetwork(ip net.IP) (string, string) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if ip == nil || c.Scope == nil || c.Scope.exclusionFor(ip) != "" {
+		return "", ""
+	}
+
+	for _, cidr := range c.Scope.CIDRs {
+		if cidr != nil && cidr.Contains(ip) {
+			return cidr.String(), "cidrs"
+		}
+	}
+	for _, a := range c.Scope.Addresses {
+		if a.Equal(ip) {
+			return c.Scope.addrEntry(a), "ips"
+		}
+	}
+	if addr, ok := ipToAddr(ip); ok {
+		for _, r := range c.Scope.hostRanges {
+			if r.contains(addr) {
+				return c.Scope.addrEntry(ip), "ips"
+			}
+		}
+	}
+	return "", ""
+}
+
+// whichExclusion returns the scope exclusion that the IP address in the parameter falls within.
+func (c *Config) whichExclusion(ip net.IP) string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if ip == nil || c.Scope == nil {
+		return ""
+	}
+	return c.Scope.exclusionFor(ip)
+}
+
+// ipEntry is an entry of the ips section along with the range of addresses it covers.
+type ipEntry struct {
+	entry string
+	r     addrRange
+}
+
+// addrEntry returns the entry in the ips section, such as an address range, that contains the address.
+func (s *Scope) addrEntry(ip net.IP) string {
+	if addr, ok := ipToAddr(ip); ok {
+		for _, e := range s.ipEntries {
+			if e.r.contains(addr) {
+				return e.entry
+			}
+		}
+	}
+	return ip.String()
+}
+
+// exclusionFor returns the exclusion that the IP address falls within.
+func (s *Scope) exclusionFor(ip net.IP) string {
+	for _, ipnet := range s.ExcludedNets {
+		if ipnet != nil && ipnet.Contains(ip) {
+			return ipnet.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/contact"
+	"github.com/owasp-amass/open-asset-model/domain"
+	"github.com/owasp-amass/open-asset-model/network"
+	"github.com/owasp-amass/open-asset-model/people"
+	"github.com/owasp-amass/open-asset-model/url"
+	"gopkg.in/yaml.v3"
+)
+
+func TestInScope(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - owasp.org
+  ips:
+    - 192.168.1.5
+  cidrs:
+    - 10.0.0.0/16
+  asns:
+    - 26808
+  ports:
+    - 80
+    - 443
+  blacklist:
+    - internal.owasp.org
+  exclusions:
+    - 10.0.5.0/24`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+	c.AddDomains(c.Scope.Domains...)
+
+	tests := []struct {
+		name    string
+		asset   oam.Asset
+		want    bool
+		partial bool
+		match   string
+	}{
+		{
+			name:  "fqdn in scope",
+			asset: &domain.FQDN{Name: "www.OWASP.org"},
+			want:  true,
+			match: "owasp.org",
+		},
+		{
+			name:  "fqdn value in scope",
+			asset: domain.FQDN{Name: "owasp.org"},
+			want:  true,
+			match: "owasp.org",
+		},
+		{
+			name:  "fqdn out of scope",
+			asset: &domain.FQDN{Name: "example.com"},
+		},
+		{
+			name:  "blacklisted fqdn",
+			asset: &domain.FQDN{Name: "a.internal.owasp.org"},
+			match: "internal.owasp.org",
+		},
+		{
+			name:  "ip address within cidr",
+			asset: &network.IPAddress{Address: netip.MustParseAddr("10.0.3.4"), Type: "IPv4"},
+			want:  true,
+			match: "10.0.0.0/16",
+		},
+		{
+			name:  "ip address in scope",
+			asset: &network.IPAddress{Address: netip.MustParseAddr("192.168.1.5"), Type: "IPv4"},
+			want:  true,
+			match: "192.168.1.5",
+		},
+		{
+			name:  "ip address out of scope",
+			asset: &network.IPAddress{Address: netip.MustParseAddr("10.1.0.1"), Type: "IPv4"},
+		},
+		{
+			name:  "excluded ip address",
+			asset: &network.IPAddress{Address: netip.MustParseAddr("10.0.5.4"), Type: "IPv4"},
+			match: "10.0.5.0/24",
+		},
+		{
+			name:  "excluded netblock",
+			asset: &network.Netblock{CIDR: netip.MustParsePrefix("10.0.5.128/25"), Type: "IPv4"},
+			match: "10.0.5.0/24",
+		},
+		{
+			name:  "netblock within cidr",
+			asset: &network.Netblock{CIDR: netip.MustParsePrefix("10.0.8.0/24"), Type: "IPv4"},
+			want:  true,
+			match: "10.0.0.0/16",
+		},
+		{
+			name:    "netblock containing an address",
+			asset:   &network.Netblock{CIDR: netip.MustParsePrefix("192.168.0.0/16"), Type: "IPv4"},
+			partial: true,
+			match:   "192.168.1.5",
+		},
+		{
+			name:    "netblock containing a cidr",
+			asset:   &network.Netblock{CIDR: netip.MustParsePrefix("10.0.0.0/8"), Type: "IPv4"},
+			partial: true,
+			match:   "10.0.0.0/16",
+		},
+		{
+			name:    "netblock overlapping an exclusion",
+			asset:   &network.Netblock{CIDR: netip.MustParsePrefix("10.0.4.0/23"), Type: "IPv4"},
+			partial: true,
+			match:   "10.0.5.0/24",
+		},
+		{
+			name:  "netblock of a single address",
+			asset: &network.Netblock{CIDR: netip.MustParsePrefix("192.168.1.5/32"), Type: "IPv4"},
+			want:  true,
+			match: "192.168.1.5",
+		},
+		{
+			name:  "netblock out of scope",
+			asset: &network.Netblock{CIDR: netip.MustParsePrefix("172.16.0.0/12"), Type: "IPv4"},
+		},
+		{
+			name:  "ipv4-mapped netblock within cidr",
+			asset: &network.Netblock{CIDR: netip.MustParsePrefix("::ffff:10.0.8.0/120"), Type: "IPv6"},
+			want:  true,
+			match: "10.0.0.0/16",
+		},
+		{
+			name:  "ipv4-mapped netblock shorter than /96",
+			asset: &network.Netblock{CIDR: netip.MustParsePrefix("::ffff:0:0/80"), Type: "IPv6"},
+		},
+		{
+			name:  "autonomous system in scope",
+			asset: &network.AutonomousSystem{Number: 26808},
+			want:  true,
+			match: "26808",
+		},
+		{
+			name:  "autonomous system out of scope",
+			asset: &network.AutonomousSystem{Number: 15169},
+		},
+		{
+			name:  "url in scope",
+			asset: &url.URL{Raw: "https://www.owasp.org/index.html", Host: "www.owasp.org"},
+			want:  true,
+			match: "owasp.org",
+		},
+		{
+			name:  "url with address host",
+			asset: &url.URL{Raw: "http://10.0.0.1/", Host: "10.0.0.1"},
+			want:  true,
+			match: "10.0.0.0/16",
+		},
+		{
+			name:  "email address in scope",
+			asset: &contact.EmailAddress{Address: "jeff@owasp.org"},
+			want:  true,
+			match: "owasp.org",
+		},
+		{
+			name:  "socket address with port in scope",
+			asset: &network.SocketAddress{Address: netip.MustParseAddrPort("10.0.0.1:443"), Protocol: "tcp"},
+			want:  true,
+			match: "10.0.0.0/16",
+		},
+		{
+			name:  "socket address with port out of scope",
+			asset: &network.SocketAddress{Address: netip.MustParseAddrPort("10.0.0.1:22"), Protocol: "tcp"},
+			match: "10.0.0.0/16",
+		},
+		{
+			name:  "network endpoint in scope",
+			asset: &domain.NetworkEndpoint{Address: "www.owasp.org:80", Name: "www.owasp.org", Port: 80},
+			want:  true,
+			match: "owasp.org",
+		},
+		{
+			name:  "unsupported asset type",
+			asset: &people.Person{FullName: "Jeff Foley"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := c.InScope(tt.asset)
+			if v.InScope != tt.want {
+				t.Errorf("InScope() = %v, want %v: %s", v.InScope, tt.want, v.Reason)
+			}
+			if v.Partial != tt.partial {
+				t.Errorf("InScope() partial = %v, want %v: %s", v.Partial, tt.partial, v.Reason)
+			}
+			if v.Match != tt.match {
+				t.Errorf("InScope() match = %s, want %s", v.Match, tt.match)
+			}
+			if v.AssetType != tt.asset.AssetType() {
+				t.Errorf("InScope() asset type = %s, want %s", v.AssetType, tt.asset.AssetType())
+			}
+			if v.Reason == "" {
+				t.Errorf("InScope() did not provide a reason")
+			}
+		})
+	}
+}
+
+func TestNetblockVerdictMappedPrefix(t *testing.T) {
+	c := NewConfig()
+	c.Scope.CIDRStrings = []string{"0.0.0.0/0"}
+	if err := c.Scope.populate(); err != nil {
+		t.Fatal(err)
+	}
+
+	v := c.InScope(&network.Netblock{CIDR: netip.MustParsePrefix("::ffff:0:0/80"), Type: "IPv6"})
+	if v.InScope || v.Partial || !strings.Contains(v.Reason, "shorter than /96") {
+		t.Errorf("InScope() = %v, partial %v: %s, want the prefix to be rejected", v.InScope, v.Partial, v.Reason)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// labelTrie indexes a list of domain names by their labels in reverse order, so the entries that a
+// DNS name ends with are found on the path from the root of the trie, one label at a time. The trie
+// is never modified after it has been built, which allows any number of concurrent readers.
+type labelTrie struct {
+	root labelNode
+	// The length of the list the trie was built from
+	size int
+}
+
+type labelNode struct {
+	children map[string]*labelNode
+	// The list entry ending at this node, if any
+	entry string
+}
+
+// newLabelTrie returns a trie containing the domain names in the list.
+func newLabelTrie(list []string) *labelTrie {
+	t := &labelTrie{size: len(list)}
+
+	for _, entry := range list {
+		if entry == "" {
+			continue
+		}
+
+		node := &t.root
+		for rest := entry; ; {
+			label, next, more := lastLabel(rest)
+
+			child, found := node.children[label]
+			if !found {
+				if node.children == nil {
+					node.children = make(map[string]*labelNode)
+				}
+				child = new(labelNode)
+				node.children[label] = child
+			}
+			node = child
+
+			if !more {
+				break
+			}
+			rest = next
+		}
+		if node.entry == "" {
+			node.entry = entry
+		}
+	}
+	return t
+}
+
+// shortestMatch returns the shortest entry that the name ends with, or an empty string.
+func (t *labelTrie) shortestMatch(name string) string {
+	node := &t.root
+
+	for rest := name; rest != ""; {
+		label, next, more := lastLabel(rest)
+
+		child, found := node.children[label]
+		if !found {
+			break
+		}
+		node = child
+
+		if node.entry != "" {
+			return node.entry
+		}
+		if !more {
+			break
+		}
+		rest = next
+	}
+	return ""
+}
+
+// longestMatch returns the longest entry that the name ends with and that the allow function permits
+// for the name, or an empty string.
+func (t *labelTrie) longestMatch(name string, allow func(name, entry string) bool) string {
+	var match string
+	node := &t.root
+
+	for rest := name; rest != ""; {
+		label, next, more := lastLabel(rest)
+
+		child, found := node.children[label]
+		if !found {
+			break
+		}
+		node = child
+
+		if node.entry != "" && allow(name, node.entry) {
+			match = node.entry
+		}
+		if !more {
+			break
+		}
+		rest = next
+	}
+	return match
+}
+
+// lastLabel splits the right-most label from the name, and reports whether labels remain to the left.
+func lastLabel(name string) (label, rest string, more bool) {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i+1:], name[:i], true
+	}
+	return name, "", false
+}
+
+// currentTrie returns the trie stored in the index, and otherwise builds and stores a new trie for the
+// list. The index must be cleared, while holding the lock that guards the list, whenever the list changes.
+// A trie built from a list of a different length is also rebuilt, which catches most direct assignments.
+func currentTrie(index *atomic.Pointer[labelTrie], list []string) *labelTrie {
+	t := index.Load()
+
+	if t == nil || t.size != len(list) {
+		t = newLabelTrie(list)
+		index.Store(t)
+	}
+	return t
+}
@@ -0,0 +1,200 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLabelTrie(t *testing.T) {
+	trie := newLabelTrie([]string{"owasp.org", "api.owasp.org", "example.com", "", "com.au"})
+
+	tests := []struct {
+		name     string
+		shortest string
+		longest  string
+	}{
+		{name: "owasp.org", shortest: "owasp.org", longest: "owasp.org"},
+		{name: "v1.api.owasp.org", shortest: "owasp.org", longest: "api.owasp.org"},
+		{name: "www.owasp.org", shortest: "owasp.org", longest: "owasp.org"},
+		{name: "notowasp.org"},
+		{name: "org"},
+		{name: "shop.example.com.au", shortest: "com.au", longest: "com.au"},
+		{name: "example.com.evil.net"},
+		{name: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trie.shortestMatch(tt.name); got != tt.shortest {
+				t.Errorf("shortestMatch() = %s, want %s", got, tt.shortest)
+			}
+			allowAll := func(name, entry string) bool { return true }
+			if got := trie.longestMatch(tt.name, allowAll); got != tt.longest {
+				t.Errorf("longestMatch() = %s, want %s", got, tt.longest)
+			}
+		})
+	}
+
+	// The longest match that is permitted is returned
+	noAPI := func(name, entry string) bool { return entry != "api.owasp.org" }
+	if got := trie.longestMatch("v1.api.owasp.org", noAPI); got != "owasp.org" {
+		t.Errorf("longestMatch() = %s, want owasp.org", got)
+	}
+}
+
+func TestCurrentTrie(t *testing.T) {
+	var index atomic.Pointer[labelTrie]
+
+	list := []string{"owasp.org"}
+	first := currentTrie(&index, list)
+	if currentTrie(&index, []string{"example.com"}) != first {
+		t.Errorf("currentTrie() rebuilt the trie before the index was cleared")
+	}
+
+	index.Store(nil)
+	if got := currentTrie(&index, []string{"example.com"}).shortestMatch("www.example.com"); got != "example.com" {
+		t.Errorf("shortestMatch() = %s, want example.com", got)
+	}
+	// A list of a different length is detected without clearing the index
+	if got := currentTrie(&index, []string{"example.com", "example.net"}).shortestMatch("www.example.net"); got != "example.net" {
+		t.Errorf("shortestMatch() = %s, want example.net", got)
+	}
+}
+
+func TestWhichDomainIndexUpdates(t *testing.T) {
+	c := NewConfig()
+	c.Scope.Domains = []string{"owasp.org", "example.com", "example.net"}
+
+	if got := c.WhichDomain("www.owasp.org"); got != "owasp.org" {
+		t.Errorf("WhichDomain() = %s, want owasp.org", got)
+	}
+	// The methods of the Config keep the index up to date
+	c.AddDomain("www.owasp.org")
+	if got := c.WhichDomain("a.www.owasp.org"); got != "www.owasp.org" {
+		t.Errorf("WhichDomain() = %s, want www.owasp.org", got)
+	}
+	_ = c.RemoveDomain("www.owasp.org")
+	if got := c.WhichDomain("a.www.owasp.org"); got != "owasp.org" {
+		t.Errorf("WhichDomain() = %s, want owasp.org", got)
+	}
+	// Entries changed in place are found after the scope is reindexed
+	c.Scope.Domains[slices.Index(c.Scope.Domains, "example.com")] = "example.org"
+	c.ReindexScope()
+	if got := c.WhichDomain("www.example.org"); got != "example.org" {
+		t.Errorf("WhichDomain() = %s, want example.org", got)
+	}
+	if got := c.WhichDomain("www.example.com"); got != "" {
+		t.Errorf("WhichDomain() = %s for a removed entry", got)
+	}
+
+	c.BlacklistSubdomain("internal.owasp.org")
+	if !c.Blacklisted("vpn.internal.owasp.org") {
+		t.Errorf("Blacklisted() = false after adding the entry")
+	}
+	_ = c.UnblacklistSubdomain("internal.owasp.org")
+	if c.Blacklisted("vpn.internal.owasp.org") {
+		t.Errorf("Blacklisted() = true after removing the entry")
+	}
+	// Entries appended directly are found without reindexing, since the length of the list changed
+	c.Scope.Domains = append(c.Scope.Domains, "example.info")
+	if got := c.WhichDomain("www.example.info"); got != "example.info" {
+		t.Errorf("WhichDomain() = %s, want example.info", got)
+	}
+
+	c.Scope.Blacklist = []string{"dev.owasp.org"}
+	c.ReindexScope()
+	if !c.Blacklisted("a.dev.owasp.org") {
+		t.Errorf("Blacklisted() = false after reindexing the blacklist")
+	}
+}
+
+// linearWhich is the previous implementation of the domain and blacklist lookups, kept for the benchmarks.
+func linearWhich(c *Config, name string, list []string) string {
+	c.Lock()
+	defer c.Unlock()
+
+	n := normalizeQuery(name)
+	for _, d := range list {
+		if hasPathSuffix(n, d) {
+			return d
+		}
+	}
+	return ""
+}
+
+func benchmarkConfig(size int) *Config {
+	c := NewConfig()
+
+	for i := 0; i < size; i++ {
+		c.Scope.Domains = append(c.Scope.Domains, fmt.Sprintf("domain%d.com", i))
+		c.Scope.Blacklist = append(c.Scope.Blacklist, fmt.Sprintf("internal.domain%d.com", i))
+	}
+	return c
+}
+
+func BenchmarkWhichDomain(b *testing.B) {
+	for _, size := range []int{10, 1000, 10000} {
+		c := benchmarkConfig(size)
+		// A name under the last root in the list is the worst case for the linear scan
+		name := fmt.Sprintf("www.domain%d.com", size-1)
+
+		b.Run(fmt.Sprintf("trie/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = c.WhichDomain(name)
+			}
+		})
+		b.Run(fmt.Sprintf("linear/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = linearWhich(c, name, c.Scope.Domains)
+			}
+		})
+	}
+}
+
+func BenchmarkBlacklisted(b *testing.B) {
+	for _, size := range []int{10, 1000, 10000} {
+		c := benchmarkConfig(size)
+		// A name that is not blacklisted requires the linear scan to check every entry
+		name := fmt.Sprintf("www.domain%d.com", size-1)
+
+		b.Run(fmt.Sprintf("trie/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = c.Blacklisted(name)
+			}
+		})
+		b.Run(fmt.Sprintf("linear/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = linearWhich(c, name, c.Scope.Blacklist) != ""
+			}
+		})
+	}
+}
+
+func BenchmarkWhichDomainParallel(b *testing.B) {
+	c := benchmarkConfig(1000)
+	name := "www.domain999.com"
+
+	b.Run("trie", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = c.WhichDomain(name)
+			}
+		})
+	})
+	b.Run("linear", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = linearWhich(c, name, c.Scope.Domains)
+			}
+		})
+	})
+}
@@ -0,0 +1,182 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// The file extensions that identify a file path in the lists of domain names.
+var listFileExtensions = []string{".txt", ".lst", ".list", ".gz"}
+
+// scopeList describes a list in the scope that can contain file paths, and how its entries are validated.
+type scopeList struct {
+	name    string
+	entries *[]string
+	domains bool
+	valid   func(entry string) error
+}
+
+// expandListFiles replaces the file paths found in the scope lists with the entries read from those files.
+// An entry is treated as a file path when it cannot be parsed as an entry of the list, or is in a list of
+// domain names and contains a path separator or ends with a file extension, such as .txt or .gz. Relative
+// paths are resolved from the directory of the configuration file, and gzip files are supported.
+func (c *Config) expandListFiles(s *Scope) error {
+	if s == nil {
+		return nil
+	}
+
+	lists := []scopeList{
+		{name: "domains", entries: &s.Domains, domains: true, valid: validDomainEntry},
+		{name: "blacklist", entries: &s.Blacklist, domains: true, valid: validDomainEntry},
+		{name: "ips", entries: &s.IP, valid: validAddrEntry},
+		{name: "cidrs", entries: &s.CIDRStrings, valid: validCIDREntry},
+		{name: "exclusions", entries: &s.Exclusions, valid: validExclusionEntry},
+	}
+
+	var errs []error
+	for _, l := range lists {
+		entries, err := c.expandList(l, *l.entries)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		*l.entries = entries
+	}
+
+	if len(s.asnEntries) > 0 {
+		asns, err := c.expandList(scopeList{name: "asns", valid: validASNEntry}, s.asnEntries)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, a := range asns {
+			num, _ := parseASNEntry(a)
+			s.ASNs = append(s.ASNs, num)
+		}
+		s.asnEntries = nil
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) expandList(l scopeList, entries []string) ([]string, error) {
+	var errs []error
+	var results []string
+
+	for _, entry := range entries {
+		e := strings.TrimSpace(entry)
+
+		verr := l.valid(e)
+		if verr == nil && !(l.domains && isDomainListFile(e)) {
+			results = append(results, e)
+			continue
+		}
+
+		path, err := c.AbsPathFromConfigDir(e)
+		if err != nil {
+			// Report the entry as invalid when it does not look like a file path
+			if verr != nil && !strings.ContainsAny(e, `/\`) {
+				errs = append(errs, fmt.Errorf("invalid %s entry: %v", l.name, verr))
+			} else {
+				errs = append(errs, fmt.Errorf("failed to get absolute path for the %s file: %w", l.name, err))
+			}
+			continue
+		}
+
+		lines, err := readListFile(path, l.valid)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		results = append(results, lines...)
+	}
+	return results, errors.Join(errs...)
+}
+
+// readListFile reads the entries from the text or gzip file, skipping blank lines and comments.
+// Every line that is not a valid entry is reported with the file path and line number.
+func readListFile(path string, valid func(string) error) ([]string, error) {
+	reader, closeFile, err := openListFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	var errs []error
+	var entries []string
+	scanner := bufio.NewScanner(reader)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := valid(line); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %v", path, num, err))
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("error reading the file %s: %v", path, err))
+	}
+	return entries, errors.Join(errs...)
+}
+
+// isDomainListFile returns true if the entry in a list of domain names contains
+// a path separator or ends with a file extension.
+func isDomainListFile(entry string) bool {
+	if strings.ContainsAny(entry, `/\`) {
+		return true
+	}
+	for _, ext := range listFileExtensions {
+		if strings.HasSuffix(strings.ToLower(entry), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func validDomainEntry(entry string) error {
+	_, err := NormalizeDomain(entry)
+	return err
+}
+
+func validAddrEntry(entry string) error {
+	_, err := parseAddrRange(entry)
+	return err
+}
+
+func validCIDREntry(entry string) error {
+	if _, _, err := net.ParseCIDR(entry); err != nil {
+		return fmt.Errorf("%s is not a valid CIDR", entry)
+	}
+	return nil
+}
+
+func validExclusionEntry(entry string) error {
+	_, err := parseExclusion(entry)
+	return err
+}
+
+func validASNEntry(entry string) error {
+	_, err := parseASNEntry(entry)
+	return err
+}
+
+// parseASNEntry parses an autonomous system number, with or without the AS prefix.
+func parseASNEntry(entry string) (int, error) {
+	e := strings.TrimSpace(entry)
+
+	num, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(e), "AS"))
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("%s is not a valid ASN", e)
+	}
+	return num, nil
+}
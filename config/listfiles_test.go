@@ -0,0 +1,158 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func writeListFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasSuffix(name, ".gz") {
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz := gzip.NewWriter(f)
+		if _, err := gz.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		gz.Close()
+		f.Close()
+	}
+}
+
+func TestExpandListFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeListFiles(t, dir, map[string]string{
+		"domains.txt":       "# client domains\nowasp.org\n\nexample.com\n",
+		"lists/blacklist":   "internal.owasp.org\n",
+		"lists/ips.txt.gz":  "192.0.2.1\n192.0.2.10-20\n",
+		"lists/cidrs.lst":   "10.0.0.0/16\n",
+		"lists/asns.txt":    "26808\nAS13335\n",
+		"lists/exclude.txt": "10.0.5.0/24\n",
+	})
+
+	c := NewConfig()
+	c.Filepath = filepath.Join(dir, "config.yaml")
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - domains.txt
+    - example.org
+  blacklist:
+    - ./lists/blacklist
+  ips:
+    - lists/ips.txt.gz
+  cidrs:
+    - 172.16.0.0/12
+    - lists/cidrs.lst
+  asns:
+    - 1234
+    - lists/asns.txt
+  exclusions:
+    - lists/exclude.txt`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{name: "domains", got: sortedSet(c.Scope.Domains), want: []string{"example.com", "example.org", "owasp.org"}},
+		{name: "blacklist", got: c.Scope.Blacklist, want: []string{"internal.owasp.org"}},
+		{name: "ips", got: c.Scope.IP, want: []string{"192.0.2.1", "192.0.2.10-20"}},
+		{name: "cidrs", got: c.Scope.CIDRStrings, want: []string{"172.16.0.0/12", "10.0.0.0/16"}},
+		{name: "asns", got: c.Scope.ASNs, want: []int{1234, 26808, 13335}},
+		{name: "exclusions", got: c.Scope.Exclusions, want: []string{"10.0.5.0/24"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+
+	if len(c.Scope.Addresses) != 12 {
+		t.Errorf("Addresses contains %d addresses, want 12", len(c.Scope.Addresses))
+	}
+}
+
+func TestExpandListFilesErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeListFiles(t, dir, map[string]string{
+		"domains.txt": "owasp.org\nbad..name\nexample.com\n",
+		"cidrs.txt":   "10.0.0.0/16\n10.0.0.1\n",
+	})
+
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "invalid lines in a domain file",
+			data: "scope:\n  domains:\n    - domains.txt\n",
+			want: []string{"domains.txt:2:"},
+		},
+		{
+			name: "invalid lines in a cidr file",
+			data: "scope:\n  cidrs:\n    - cidrs.txt\n",
+			want: []string{"cidrs.txt:2:", "10.0.0.1 is not a valid CIDR"},
+		},
+		{
+			name: "missing file",
+			data: "scope:\n  domains:\n    - missing.txt\n",
+			want: []string{"file does not exist"},
+		},
+		{
+			name: "invalid inline entry",
+			data: "scope:\n  asns:\n    - ASX\n",
+			want: []string{"ASX is not a valid ASN"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			c.Filepath = filepath.Join(dir, "config.yaml")
+			if err := yaml.Unmarshal([]byte(tt.data), c); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.loadSeedandScopeSettings()
+			if err == nil {
+				t.Fatalf("loadSeedandScopeSettings() expected an error")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("loadSeedandScopeSettings() error = %v, want it to contain %s", err, w)
+				}
+			}
+		})
+	}
+}
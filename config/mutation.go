@@ -0,0 +1,722 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ScopeChange identifies the kind of change made to the scope.
+type ScopeChange string
+
+// The changes published to the scope subscribers.
+const (
+	DomainAdded         ScopeChange = "domain_added"
+	DomainRemoved       ScopeChange = "domain_removed"
+	BlacklistAdded      ScopeChange = "blacklist_added"
+	BlacklistRemoved    ScopeChange = "blacklist_removed"
+	AddressAdded        ScopeChange = "address_added"
+	AddressRemoved      ScopeChange = "address_removed"
+	CIDRAdded           ScopeChange = "cidr_added"
+	CIDRRemoved         ScopeChange = "cidr_removed"
+	ASNAdded            ScopeChange = "asn_added"
+	ASNRemoved          ScopeChange = "asn_removed"
+	ExclusionAdded      ScopeChange = "exclusion_added"
+	ExclusionRemoved    ScopeChange = "exclusion_removed"
+	OrganizationAdded   ScopeChange = "organization_added"
+	OrganizationRemoved ScopeChange = "organization_removed"
+	EmailAdded          ScopeChange = "email_added"
+	EmailRemoved        ScopeChange = "email_removed"
+	URLAdded            ScopeChange = "url_added"
+	URLRemoved          ScopeChange = "url_removed"
+	PortAdded           ScopeChange = "port_added"
+	PortRemoved         ScopeChange = "port_removed"
+)
+
+// errNoScope is returned when an entry is added to a configuration without a scope.
+var errNoScope = errors.New("the configuration does not have a scope")
+
+// ScopeEvent describes a change made to the scope at runtime.
+type ScopeEvent struct {
+	Change ScopeChange `json:"change"`
+	// The normalized scope entry that was added or removed
+	Value string `json:"value"`
+}
+
+// scopeSubscriber is a function registered to receive the scope events.
+type scopeSubscriber struct {
+	id uint64
+	fn func(ScopeEvent)
+}
+
+// SubscribeScope registers the function to be called with each change made to the scope, and returns
+// the function that removes the subscription. The events are delivered synchronously, in the order of
+// the changes, after the scope locks have been released, so the subscriber can query the scope.
+func (c *Config) SubscribeScope(fn func(ScopeEvent)) (unsubscribe func()) {
+	c.scopeSubsLock.Lock()
+	defer c.scopeSubsLock.Unlock()
+
+	c.nextScopeSub++
+	id := c.nextScopeSub
+	c.scopeSubs = append(c.scopeSubs, &scopeSubscriber{id: id, fn: fn})
+
+	return func() {
+		c.scopeSubsLock.Lock()
+		defer c.scopeSubsLock.Unlock()
+
+		for i, sub := range c.scopeSubs {
+			if sub.id == id {
+				c.scopeSubs = append(c.scopeSubs[:i:i], c.scopeSubs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// publishScopeEvent delivers the event to the subscribers. It must not be called while holding the scope locks.
+func (c *Config) publishScopeEvent(change ScopeChange, value string) {
+	c.scopeSubsLock.Lock()
+	subs := make([]*scopeSubscriber, len(c.scopeSubs))
+	copy(subs, c.scopeSubs)
+	c.scopeSubsLock.Unlock()
+
+	for _, sub := range subs {
+		sub.fn(ScopeEvent{Change: change, Value: value})
+	}
+}
+
+// RemoveDomain removes the domain name provided in the parameter, and its options, from the list in the
+// configuration. Removing a domain that is not in the list has no effect.
+func (c *Config) RemoveDomain(domain string) error {
+	d, err := NormalizeDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	domains, removed := removeString(c.Scope.Domains, d)
+	c.Scope.Domains = domains
+	c.domainIndex.Store(nil)
+	delete(c.regexps, d)
+	delete(c.Scope.DomainOptions, d)
+	c.Unlock()
+
+	if removed {
+		c.publishScopeEvent(DomainRemoved, d)
+	}
+	return nil
+}
+
+// UnblacklistSubdomain removes a subdomain name from the config blacklist.
+// Removing a name that is not in the blacklist has no effect.
+func (c *Config) UnblacklistSubdomain(name string) error {
+	n, err := NormalizeDomain(name)
+	if err != nil {
+		return err
+	}
+
+	c.blacklistLock.Lock()
+	if c.Scope == nil {
+		c.blacklistLock.Unlock()
+		return nil
+	}
+	blacklist, removed := removeString(c.Scope.Blacklist, n)
+	c.Scope.Blacklist = blacklist
+	c.blacklistIndex.Store(nil)
+	c.blacklistLock.Unlock()
+
+	if removed {
+		c.publishScopeEvent(BlacklistRemoved, n)
+	}
+	return nil
+}
+
+// AddAddress adds the IP address provided in the parameter to the scope.
+// The reserved address space policy is applied to the address.
+func (c *Config) AddAddress(addr string) error {
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if ip == nil {
+		return fmt.Errorf("%s is not a valid IP address", addr)
+	}
+	a := ip.String()
+	if addr, ok := ipToAddr(ip); ok {
+		if err := c.checkReservedRange(addrRange{start: addr, end: addr}); err != nil {
+			return err
+		}
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return errNoScope
+	}
+	var found bool
+	for _, existing := range c.Scope.Addresses {
+		if existing.Equal(ip) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.Scope.IP = append(c.Scope.IP, a)
+		c.Scope.Addresses = append(c.Scope.Addresses, ip)
+		if addr, ok := ipToAddr(ip); ok {
+			c.Scope.ipEntries = append(c.Scope.ipEntries, ipEntry{entry: a, r: addrRange{start: addr, end: addr}})
+		}
+	}
+	c.Unlock()
+
+	if !found {
+		c.publishScopeEvent(AddressAdded, a)
+	}
+	return nil
+}
+
+// RemoveAddress removes the IP address provided in the parameter from the scope. An address that is
+// part of a range in the ips section cannot be removed individually, and should be excluded instead.
+func (c *Config) RemoveAddress(addr string) error {
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if ip == nil {
+		return fmt.Errorf("%s is not a valid IP address", addr)
+	}
+	a := ip.String()
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	if entry := c.Scope.addrEntry(ip); entry != a && strings.Contains(entry, "-") {
+		c.Unlock()
+		return fmt.Errorf("%s is part of the range %s and must be excluded instead", a, entry)
+	}
+
+	var removed bool
+	var addrs []net.IP
+	for _, existing := range c.Scope.Addresses {
+		if existing.Equal(ip) {
+			removed = true
+			continue
+		}
+		addrs = append(addrs, existing)
+	}
+	c.Scope.Addresses = addrs
+
+	var entries []string
+	for _, entry := range c.Scope.IP {
+		if e := net.ParseIP(strings.TrimSpace(entry)); e == nil || !e.Equal(ip) {
+			entries = append(entries, entry)
+		}
+	}
+	c.Scope.IP = entries
+
+	if addr, ok := ipToAddr(ip); ok {
+		var parsed []ipEntry
+		for _, e := range c.Scope.ipEntries {
+			if !e.r.isSingle() || !e.r.contains(addr) {
+				parsed = append(parsed, e)
+			}
+		}
+		c.Scope.ipEntries = parsed
+	}
+	c.Unlock()
+
+	if removed {
+		c.publishScopeEvent(AddressRemoved, a)
+	}
+	return nil
+}
+
+// AddCIDR adds the CIDR provided in the parameter to the scope.
+// The reserved address space policy is applied to the CIDR.
+func (c *Config) AddCIDR(cidr string) error {
+	_, ipnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return fmt.Errorf("%s is not a valid CIDR", cidr)
+	}
+	n := ipnet.String()
+	if p, ok := ipNetToPrefix(ipnet); ok {
+		if err := c.checkReservedRange(prefixRange(p)); err != nil {
+			return err
+		}
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return errNoScope
+	}
+	var found bool
+	for _, existing := range c.Scope.CIDRs {
+		if existing != nil && existing.String() == n {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.Scope.CIDRStrings = append(c.Scope.CIDRStrings, n)
+		c.Scope.CIDRs = append(c.Scope.CIDRs, ipnet)
+	}
+	c.Unlock()
+
+	if !found {
+		c.publishScopeEvent(CIDRAdded, n)
+	}
+	return nil
+}
+
+// RemoveCIDR removes the CIDR provided in the parameter from the scope. Removing a CIDR that is not
+// in the scope has no effect. Use AddExclusion to remove part of a CIDR from the scope.
+func (c *Config) RemoveCIDR(cidr string) error {
+	_, ipnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return fmt.Errorf("%s is not a valid CIDR", cidr)
+	}
+	n := ipnet.String()
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	var removed bool
+	var strs []string
+	for _, s := range c.Scope.CIDRStrings {
+		if _, existing, err := net.ParseCIDR(strings.TrimSpace(s)); err == nil && existing.String() == n {
+			removed = true
+			continue
+		}
+		strs = append(strs, s)
+	}
+	c.Scope.CIDRStrings = strs
+	c.Scope.CIDRs = c.Scope.toCIDRs(strs)
+	c.Unlock()
+
+	if removed {
+		c.publishScopeEvent(CIDRRemoved, n)
+	}
+	return nil
+}
+
+// AddASN adds the autonomous system number provided in the parameter to the scope.
+func (c *Config) AddASN(asn int) error {
+	if asn < 0 {
+		return fmt.Errorf("%d is not a valid ASN", asn)
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return errNoScope
+	}
+	var found bool
+	for _, a := range c.Scope.ASNs {
+		if a == asn {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.Scope.ASNs = append(c.Scope.ASNs, asn)
+	}
+	c.Unlock()
+
+	if !found {
+		c.publishScopeEvent(ASNAdded, strconv.Itoa(asn))
+	}
+	return nil
+}
+
+// RemoveASN removes the autonomous system number provided in the parameter from the scope.
+func (c *Config) RemoveASN(asn int) error {
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	var removed bool
+	var asns []int
+	for _, a := range c.Scope.ASNs {
+		if a == asn {
+			removed = true
+			continue
+		}
+		asns = append(asns, a)
+	}
+	c.Scope.ASNs = asns
+	c.Unlock()
+
+	if removed {
+		c.publishScopeEvent(ASNRemoved, strconv.Itoa(asn))
+	}
+	return nil
+}
+
+// AddExclusion adds the IP address or CIDR provided in the parameter to the scope exclusions.
+func (c *Config) AddExclusion(exclusion string) error {
+	ipnet, err := parseExclusion(exclusion)
+	if err != nil {
+		return err
+	}
+	n := ipnet.String()
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return errNoScope
+	}
+	var found bool
+	for _, existing := range c.Scope.ExcludedNets {
+		if existing.String() == n {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.Scope.Exclusions = append(c.Scope.Exclusions, n)
+		c.Scope.ExcludedNets = append(c.Scope.ExcludedNets, ipnet)
+	}
+	c.Unlock()
+
+	if !found {
+		c.publishScopeEvent(ExclusionAdded, n)
+	}
+	return nil
+}
+
+// RemoveExclusion removes the IP address or CIDR provided in the parameter from the scope exclusions.
+func (c *Config) RemoveExclusion(exclusion string) error {
+	ipnet, err := parseExclusion(exclusion)
+	if err != nil {
+		return err
+	}
+	n := ipnet.String()
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	var removed bool
+	var entries []string
+	var nets []*net.IPNet
+	for _, e := range c.Scope.Exclusions {
+		existing, err := parseExclusion(e)
+		if err == nil && existing.String() == n {
+			removed = true
+			continue
+		}
+		entries = append(entries, e)
+		if err == nil {
+			nets = append(nets, existing)
+		}
+	}
+	c.Scope.Exclusions = entries
+	c.Scope.ExcludedNets = nets
+	c.Unlock()
+
+	if removed {
+		c.publishScopeEvent(ExclusionRemoved, n)
+	}
+	return nil
+}
+
+// AddOrganization adds the organization name provided in the parameter to the scope.
+// The name is lower-cased and the repeated whitespace is collapsed before it is added.
+func (c *Config) AddOrganization(name string) error {
+	n := normalizeOrganization(name)
+	if n == "" {
+		return fmt.Errorf("the organization name cannot be empty")
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return errNoScope
+	}
+	found := slices.Contains(c.Scope.Organizations, n)
+	if !found {
+		c.Scope.Organizations = sortedSet(append(c.Scope.Organizations, n))
+	}
+	c.Unlock()
+
+	if !found {
+		c.publishScopeEvent(OrganizationAdded, n)
+	}
+	return nil
+}
+
+// RemoveOrganization removes the organization name provided in the parameter from the scope.
+func (c *Config) RemoveOrganization(name string) error {
+	n := normalizeOrganization(name)
+	if n == "" {
+		return fmt.Errorf("the organization name cannot be empty")
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	orgs, removed := removeString(c.Scope.Organizations, n)
+	c.Scope.Organizations = orgs
+	c.Unlock()
+
+	if removed {
+		c.publishScopeEvent(OrganizationRemoved, n)
+	}
+	return nil
+}
+
+// AddEmail adds the email address provided in the parameter to the scope. An address starting
+// with @, such as @example.com, represents every mailbox at the domain.
+func (c *Config) AddEmail(email string) error {
+	e, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return errNoScope
+	}
+	found := slices.Contains(c.Scope.Emails, e)
+	if !found {
+		c.Scope.Emails = sortedSet(append(c.Scope.Emails, e))
+	}
+	c.Unlock()
+
+	if !found {
+		c.publishScopeEvent(EmailAdded, e)
+	}
+	return nil
+}
+
+// RemoveEmail removes the email address provided in the parameter from the scope.
+func (c *Config) RemoveEmail(email string) error {
+	e, err := NormalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	emails, removed := removeString(c.Scope.Emails, e)
+	c.Scope.Emails = emails
+	c.Unlock()
+
+	if removed {
+		c.publishScopeEvent(EmailRemoved, e)
+	}
+	return nil
+}
+
+// AddURL adds the URL provided in the parameter to the scope. The scheme and host are required,
+// and the path is used as a prefix for the URLs in scope.
+func (c *Config) AddURL(rawURL string) error {
+	prefix, err := parseURLPrefix(rawURL)
+	if err != nil {
+		return err
+	}
+	u := prefix.String()
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return errNoScope
+	}
+	var found bool
+	for _, existing := range c.Scope.URLPrefixes {
+		if existing.String() == u {
+			found = true
+			break
+		}
+	}
+	if !found {
+		// The URLs and their prefixes are kept in the same order, since WhichURL pairs them by index
+		if len(c.Scope.URLs) != len(c.Scope.URLPrefixes) {
+			c.Scope.URLs = nil
+			for _, existing := range c.Scope.URLPrefixes {
+				c.Scope.URLs = append(c.Scope.URLs, existing.String())
+			}
+		}
+		c.Scope.URLs = append(c.Scope.URLs, u)
+		c.Scope.URLPrefixes = append(c.Scope.URLPrefixes, prefix)
+	}
+	c.Unlock()
+
+	if !found {
+		c.publishScopeEvent(URLAdded, u)
+	}
+	return nil
+}
+
+// RemoveURL removes the URL provided in the parameter from the scope. Only an entry with the same
+// scheme, host, port and path is removed, and the URLs below it remain in scope through the entry.
+func (c *Config) RemoveURL(rawURL string) error {
+	prefix, err := parseURLPrefix(rawURL)
+	if err != nil {
+		return err
+	}
+	u := prefix.String()
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	var removed bool
+	var urls []string
+	var prefixes []*neturl.URL
+	for i, existing := range c.Scope.URLPrefixes {
+		if existing.String() == u {
+			removed = true
+			continue
+		}
+		if i < len(c.Scope.URLs) {
+			urls = append(urls, c.Scope.URLs[i])
+		} else {
+			urls = append(urls, existing.String())
+		}
+		prefixes = append(prefixes, existing)
+	}
+	c.Scope.URLs = urls
+	c.Scope.URLPrefixes = prefixes
+	c.Unlock()
+
+	if removed {
+		c.publishScopeEvent(URLRemoved, u)
+	}
+	return nil
+}
+
+// AddPorts adds the port specification provided in the parameter, such as "443", "8000-8100", "53/udp"
+// or a named set like "web", to the scope.
+func (c *Config) AddPorts(spec string) error {
+	p, err := normalizePortSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return errNoScope
+	}
+	specs := c.Scope.portSpecs()
+	found := slices.ContainsFunc(specs, func(s string) bool {
+		n, err := normalizePortSpec(s)
+		return err == nil && n == p
+	})
+	if !found {
+		err = c.Scope.setPortSpecs(append(specs[:len(specs):len(specs)], p))
+	}
+	c.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if !found {
+		c.publishScopeEvent(PortAdded, p)
+	}
+	return nil
+}
+
+// RemovePorts removes the port specification provided in the parameter from the scope. Only an
+// equal specification is removed, so the ports also covered by other specifications remain in scope.
+func (c *Config) RemovePorts(spec string) error {
+	p, err := normalizePortSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	if c.Scope == nil {
+		c.Unlock()
+		return nil
+	}
+	var removed bool
+	var specs []string
+	for _, s := range c.Scope.portSpecs() {
+		if n, err := normalizePortSpec(s); err == nil && n == p {
+			removed = true
+			continue
+		}
+		specs = append(specs, s)
+	}
+	if removed {
+		err = c.Scope.setPortSpecs(specs)
+	}
+	c.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if removed {
+		c.publishScopeEvent(PortRemoved, p)
+	}
+	return nil
+}
+
+// normalizePortSpec validates the port specification and returns it in the notation of PortRange.String,
+// or the name of the port set.
+func normalizePortSpec(spec string) (string, error) {
+	ranges, err := ParsePorts(spec)
+	if err != nil {
+		return "", err
+	}
+
+	s := strings.ToLower(strings.TrimSpace(spec))
+	if _, found := PortSets[s]; found {
+		return s, nil
+	}
+	return ranges[0].String(), nil
+}
+
+// setPortSpecs replaces the port specifications of the scope and keeps the port ranges and numbers in sync.
+func (s *Scope) setPortSpecs(specs []string) error {
+	var ranges []*PortRange
+	for _, spec := range specs {
+		r, err := ParsePorts(spec)
+		if err != nil {
+			return err
+		}
+		ranges = append(ranges, r...)
+	}
+
+	s.PortStrings = specs
+	s.PortRanges = ranges
+	s.Ports = portNumbers(ranges)
+	return nil
+}
+
+// removeString returns the list without the element, and true if the element was found.
+func removeString(list []string, element string) ([]string, bool) {
+	var removed bool
+	var results []string
+
+	for _, s := range list {
+		if s == element {
+			removed = true
+			continue
+		}
+		results = append(results, s)
+	}
+	return results, removed
+}
@@ -0,0 +1,301 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestScopeMutations(t *testing.T) {
+	c := NewConfig()
+
+	var events []ScopeEvent
+	unsubscribe := c.SubscribeScope(func(e ScopeEvent) {
+		// The subscriber can query the scope while handling the event
+		_ = c.IsDomainInScope("www.owasp.org")
+		events = append(events, e)
+	})
+
+	steps := []struct {
+		name  string
+		apply func() error
+		check func() bool
+	}{
+		{
+			name:  "add domain",
+			apply: func() error { return c.AddDomainE("OWASP.org") },
+			check: func() bool { return c.IsDomainInScope("www.owasp.org") },
+		},
+		{
+			name:  "add the same domain again",
+			apply: func() error { return c.AddDomainE("owasp.org") },
+			check: func() bool { return len(c.Domains()) == 1 },
+		},
+		{
+			name:  "blacklist subdomain",
+			apply: func() error { return c.BlacklistSubdomainE("internal.owasp.org") },
+			check: func() bool { return c.Blacklisted("a.internal.owasp.org") },
+		},
+		{
+			name:  "unblacklist subdomain",
+			apply: func() error { return c.UnblacklistSubdomain("internal.owasp.org") },
+			check: func() bool { return !c.Blacklisted("a.internal.owasp.org") },
+		},
+		{
+			name:  "remove domain",
+			apply: func() error { return c.RemoveDomain("owasp.org") },
+			check: func() bool { return !c.IsDomainInScope("www.owasp.org") && c.DomainRegex("owasp.org") == nil },
+		},
+		{
+			name:  "add cidr",
+			apply: func() error { return c.AddCIDR("10.0.0.1/16") },
+			check: func() bool { return c.IsAddressInScope("10.0.3.4") },
+		},
+		{
+			name:  "add exclusion",
+			apply: func() error { return c.AddExclusion("10.0.3.0/24") },
+			check: func() bool { return !c.IsAddressInScope("10.0.3.4") },
+		},
+		{
+			name:  "remove exclusion",
+			apply: func() error { return c.RemoveExclusion("10.0.3.0/24") },
+			check: func() bool { return c.IsAddressInScope("10.0.3.4") },
+		},
+		{
+			name:  "remove cidr",
+			apply: func() error { return c.RemoveCIDR("10.0.0.0/16") },
+			check: func() bool { return !c.IsAddressInScope("10.0.3.4") && len(c.Scope.CIDRStrings) == 0 },
+		},
+		{
+			name:  "add address",
+			apply: func() error { return c.AddAddress("192.0.2.1") },
+			check: func() bool { return c.IsAddressInScope("192.0.2.1") },
+		},
+		{
+			name:  "remove address",
+			apply: func() error { return c.RemoveAddress("192.0.2.1") },
+			check: func() bool { return !c.IsAddressInScope("192.0.2.1") && len(c.Scope.IP) == 0 },
+		},
+		{
+			name:  "add asn",
+			apply: func() error { return c.AddASN(26808) },
+			check: func() bool { return reflect.DeepEqual(c.Scope.ASNs, []int{26808}) },
+		},
+		{
+			name:  "remove asn",
+			apply: func() error { return c.RemoveASN(26808) },
+			check: func() bool { return len(c.Scope.ASNs) == 0 },
+		},
+		{
+			name:  "remove missing asn",
+			apply: func() error { return c.RemoveASN(26808) },
+			check: func() bool { return len(c.Scope.ASNs) == 0 },
+		},
+		{
+			name:  "add organization",
+			apply: func() error { return c.AddOrganization("  OWASP   Foundation ") },
+			check: func() bool { return c.IsOrganizationInScope("owasp foundation") },
+		},
+		{
+			name:  "remove organization",
+			apply: func() error { return c.RemoveOrganization("OWASP Foundation") },
+			check: func() bool { return !c.IsOrganizationInScope("owasp foundation") },
+		},
+		{
+			name:  "add email",
+			apply: func() error { return c.AddEmail("@OWASP.org") },
+			check: func() bool { return c.WhichEmail("jeff@owasp.org") == "@owasp.org" },
+		},
+		{
+			name:  "remove email",
+			apply: func() error { return c.RemoveEmail("@owasp.org") },
+			check: func() bool { return c.WhichEmail("jeff@owasp.org") == "" && len(c.Scope.Emails) == 0 },
+		},
+		{
+			name:  "add url",
+			apply: func() error { return c.AddURL("HTTPS://App.example.com/login") },
+			check: func() bool {
+				return c.WhichURL("https://app.example.com/login/reset") == "https://app.example.com:443/login"
+			},
+		},
+		{
+			name:  "add the same url again",
+			apply: func() error { return c.AddURL("https://app.example.com:443/login") },
+			check: func() bool { return len(c.Scope.URLs) == 1 && len(c.Scope.URLPrefixes) == 1 },
+		},
+		{
+			name:  "remove url",
+			apply: func() error { return c.RemoveURL("https://app.example.com/login") },
+			check: func() bool { return c.WhichURL("https://app.example.com/login") == "" && len(c.Scope.URLPrefixes) == 0 },
+		},
+		{
+			name:  "add ports",
+			apply: func() error { return c.AddPorts("8000-8100/TCP") },
+			check: func() bool {
+				return c.IsPortInScope("tcp", 8050) && c.IsPortInScope("tcp", 443) && !c.IsPortInScope("udp", 8050)
+			},
+		},
+		{
+			name:  "remove ports",
+			apply: func() error { return c.RemovePorts("8000-8100/tcp") },
+			check: func() bool {
+				return !c.IsPortInScope("tcp", 8050) && reflect.DeepEqual(c.Scope.Ports, []int{80, 443})
+			},
+		},
+		{
+			name:  "remove default port",
+			apply: func() error { return c.RemovePorts("80") },
+			check: func() bool {
+				return !c.IsPortInScope("tcp", 80) && reflect.DeepEqual(c.Scope.PortStrings, []string{"443"})
+			},
+		},
+	}
+	for _, s := range steps {
+		if err := s.apply(); err != nil {
+			t.Fatalf("%s: error = %v", s.name, err)
+		}
+		if !s.check() {
+			t.Errorf("%s: the scope was not updated", s.name)
+		}
+	}
+
+	want := []ScopeEvent{
+		{Change: DomainAdded, Value: "owasp.org"},
+		{Change: BlacklistAdded, Value: "internal.owasp.org"},
+		{Change: BlacklistRemoved, Value: "internal.owasp.org"},
+		{Change: DomainRemoved, Value: "owasp.org"},
+		{Change: CIDRAdded, Value: "10.0.0.0/16"},
+		{Change: ExclusionAdded, Value: "10.0.3.0/24"},
+		{Change: ExclusionRemoved, Value: "10.0.3.0/24"},
+		{Change: CIDRRemoved, Value: "10.0.0.0/16"},
+		{Change: AddressAdded, Value: "192.0.2.1"},
+		{Change: AddressRemoved, Value: "192.0.2.1"},
+		{Change: ASNAdded, Value: "26808"},
+		{Change: ASNRemoved, Value: "26808"},
+		{Change: OrganizationAdded, Value: "owasp foundation"},
+		{Change: OrganizationRemoved, Value: "owasp foundation"},
+		{Change: EmailAdded, Value: "@owasp.org"},
+		{Change: EmailRemoved, Value: "@owasp.org"},
+		{Change: URLAdded, Value: "https://app.example.com:443/login"},
+		{Change: URLRemoved, Value: "https://app.example.com:443/login"},
+		{Change: PortAdded, Value: "8000-8100/tcp"},
+		{Change: PortRemoved, Value: "8000-8100/tcp"},
+		{Change: PortRemoved, Value: "80"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+
+	unsubscribe()
+	if err := c.AddASN(13335); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != len(want) {
+		t.Errorf("an event was delivered after unsubscribing")
+	}
+}
+
+func TestScopeMutationErrors(t *testing.T) {
+	c := NewConfig()
+	c.Scope.IP = []string{"192.0.2.10-20"}
+	if err := c.Scope.populate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		apply func() error
+	}{
+		{name: "invalid domain", apply: func() error { return c.RemoveDomain("bad..name") }},
+		{name: "invalid address", apply: func() error { return c.AddAddress("192.0.2") }},
+		{name: "address within a range", apply: func() error { return c.RemoveAddress("192.0.2.15") }},
+		{name: "invalid cidr", apply: func() error { return c.AddCIDR("192.0.2.0") }},
+		{name: "invalid asn", apply: func() error { return c.AddASN(-1) }},
+		{name: "invalid exclusion", apply: func() error { return c.AddExclusion("bad") }},
+		{name: "empty organization", apply: func() error { return c.AddOrganization("  ") }},
+		{name: "invalid email", apply: func() error { return c.AddEmail("owasp.org") }},
+		{name: "invalid url", apply: func() error { return c.AddURL("app.example.com/login") }},
+		{name: "invalid ports", apply: func() error { return c.AddPorts("80-70") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.apply(); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestScopeMutationsWithoutScope(t *testing.T) {
+	c := &Config{}
+
+	adds := []struct {
+		name  string
+		apply func() error
+	}{
+		{name: "domain", apply: func() error { return c.AddDomainE("owasp.org") }},
+		{name: "blacklist", apply: func() error { return c.BlacklistSubdomainE("internal.owasp.org") }},
+		{name: "address", apply: func() error { return c.AddAddress("192.0.2.1") }},
+		{name: "cidr", apply: func() error { return c.AddCIDR("10.0.0.0/16") }},
+		{name: "asn", apply: func() error { return c.AddASN(26808) }},
+		{name: "exclusion", apply: func() error { return c.AddExclusion("10.0.3.0/24") }},
+		{name: "organization", apply: func() error { return c.AddOrganization("OWASP") }},
+		{name: "email", apply: func() error { return c.AddEmail("jeff@owasp.org") }},
+		{name: "url", apply: func() error { return c.AddURL("https://owasp.org/") }},
+		{name: "ports", apply: func() error { return c.AddPorts("443") }},
+	}
+	for _, tt := range adds {
+		if err := tt.apply(); err == nil {
+			t.Errorf("adding the %s did not return an error", tt.name)
+		}
+	}
+
+	removes := []struct {
+		name  string
+		apply func() error
+	}{
+		{name: "domain", apply: func() error { return c.RemoveDomain("owasp.org") }},
+		{name: "blacklist", apply: func() error { return c.UnblacklistSubdomain("internal.owasp.org") }},
+		{name: "address", apply: func() error { return c.RemoveAddress("192.0.2.1") }},
+		{name: "cidr", apply: func() error { return c.RemoveCIDR("10.0.0.0/16") }},
+		{name: "asn", apply: func() error { return c.RemoveASN(26808) }},
+		{name: "exclusion", apply: func() error { return c.RemoveExclusion("10.0.3.0/24") }},
+		{name: "organization", apply: func() error { return c.RemoveOrganization("OWASP") }},
+		{name: "email", apply: func() error { return c.RemoveEmail("jeff@owasp.org") }},
+		{name: "url", apply: func() error { return c.RemoveURL("https://owasp.org/") }},
+		{name: "ports", apply: func() error { return c.RemovePorts("443") }},
+	}
+	for _, tt := range removes {
+		if err := tt.apply(); err != nil {
+			t.Errorf("removing the %s returned an error: %v", tt.name, err)
+		}
+	}
+}
+
+func TestScopeMutationsConcurrent(t *testing.T) {
+	c := NewConfig()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				_ = c.AddASN(i*100 + j)
+				_ = c.AddCIDR("10.0.0.0/8")
+				_ = c.IsAddressInScope("10.1.2.3")
+				_ = c.RemoveCIDR("10.0.0.0/8")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(c.Scope.ASNs) != 400 {
+		t.Errorf("ASNs contains %d entries, want 400", len(c.Scope.ASNs))
+	}
+}
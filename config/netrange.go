@@ -0,0 +1,287 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// addrRange represents an inclusive range of IP addresses within the same address family.
+type addrRange struct {
+	start netip.Addr
+	end   netip.Addr
+}
+
+// prefixRange returns the range of addresses covered by the prefix.
+func prefixRange(p netip.Prefix) addrRange {
+	p = p.Masked()
+	return addrRange{start: p.Addr(), end: lastAddr(p)}
+}
+
+// lastAddr returns the last address within the prefix.
+func lastAddr(p netip.Prefix) netip.Addr {
+	a := p.Masked().Addr()
+
+	if a.Is4() {
+		b := a.As4()
+		setHostBits(b[:], p.Bits())
+		return netip.AddrFrom4(b)
+	}
+
+	b := a.As16()
+	setHostBits(b[:], p.Bits())
+	return netip.AddrFrom16(b)
+}
+
+func setHostBits(b []byte, bits int) {
+	for i := range b {
+		if hb := bits - i*8; hb <= 0 {
+			b[i] = 0xff
+		} else if hb < 8 {
+			b[i] |= 0xff >> hb
+		}
+	}
+}
+
+// ipNetToPrefix converts the net.IPNet into a netip.Prefix, unmapping IPv4 addresses.
+func ipNetToPrefix(ipnet *net.IPNet) (netip.Prefix, bool) {
+	if ipnet == nil {
+		return netip.Prefix{}, false
+	}
+
+	addr, ok := netip.AddrFromSlice(ipnet.IP)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	ones, bits := ipnet.Mask.Size()
+	if bits == 0 {
+		return netip.Prefix{}, false
+	}
+	if addr.Is4In6() && bits == 32 {
+		addr = addr.Unmap()
+	}
+	return netip.PrefixFrom(addr, ones).Masked(), true
+}
+
+// ipToAddr converts the net.IP into a netip.Addr, unmapping IPv4 addresses.
+func ipToAddr(ip net.IP) (netip.Addr, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// mergeRanges sorts the ranges and combines those that overlap or are adjacent.
+func mergeRanges(ranges []addrRange) []addrRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sorted := make([]addrRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].start.Compare(sorted[j].start); c != 0 {
+			return c < 0
+		}
+		return sorted[i].end.Compare(sorted[j].end) < 0
+	})
+
+	merged := []addrRange{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+
+		if last.start.Is4() == r.start.Is4() {
+			next := last.end.Next()
+			if r.start.Compare(last.end) <= 0 || (next.IsValid() && r.start == next) {
+				if r.end.Compare(last.end) > 0 {
+					last.end = r.end
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// prefixes returns the smallest set of prefixes that exactly covers the range.
+func (r addrRange) prefixes() []netip.Prefix {
+	var results []netip.Prefix
+
+	start := r.start
+	for start.IsValid() && start.Compare(r.end) <= 0 {
+		var p netip.Prefix
+
+		for bits := 0; bits <= start.BitLen(); bits++ {
+			p = netip.PrefixFrom(start, bits)
+			if p.Masked().Addr() == start && lastAddr(p).Compare(r.end) <= 0 {
+				break
+			}
+		}
+
+		results = append(results, p)
+		start = lastAddr(p).Next()
+	}
+	return results
+}
+
+// contains returns true if the address falls within the range.
+func (r addrRange) contains(addr netip.Addr) bool {
+	return r.start.Is4() == addr.Is4() && r.start.Compare(addr) <= 0 && r.end.Compare(addr) >= 0
+}
+
+// overlaps returns true if the ranges have at least one address in common.
+func (r addrRange) overlaps(o addrRange) bool {
+	return r.start.Is4() == o.start.Is4() && r.start.Compare(o.end) <= 0 && o.start.Compare(r.end) <= 0
+}
+
+// covers returns true if every address of the other range falls within the range.
+func (r addrRange) covers(o addrRange) bool {
+	return r.contains(o.start) && r.contains(o.end)
+}
+
+// sizeAtMost returns true if the range contains no more than n addresses.
+func (r addrRange) sizeAtMost(n uint64) bool {
+	s, e := r.start.As16(), r.end.As16()
+
+	shi, slo := binary.BigEndian.Uint64(s[:8]), binary.BigEndian.Uint64(s[8:])
+	ehi, elo := binary.BigEndian.Uint64(e[:8]), binary.BigEndian.Uint64(e[8:])
+	// Compute the 128-bit difference between the end and start addresses
+	lo, borrow := bits.Sub64(elo, slo, 0)
+	hi, _ := bits.Sub64(ehi, shi, borrow)
+	return hi == 0 && lo < n
+}
+
+// isSingle returns true if the range contains only one address.
+func (r addrRange) isSingle() bool {
+	return r.start == r.end
+}
+
+// String returns the range in start-end notation, or the address when the range contains only one.
+func (r addrRange) String() string {
+	if r.isSingle() {
+		return r.start.String()
+	}
+	return r.start.String() + "-" + r.end.String()
+}
+
+// networkRanges returns the merged address ranges covered by the CIDRs and addresses provided.
+func networkRanges(cidrs []*net.IPNet, addrs []net.IP) []addrRange {
+	var ranges []addrRange
+
+	for _, cidr := range cidrs {
+		if p, ok := ipNetToPrefix(cidr); ok {
+			ranges = append(ranges, prefixRange(p))
+		}
+	}
+	for _, ip := range addrs {
+		if a, ok := ipToAddr(ip); ok {
+			ranges = append(ranges, addrRange{start: a, end: a})
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// parseAddrRange parses an IP address or range, using the notation accepted in the scope ips section,
+// into the address range it represents without expanding the addresses.
+func parseAddrRange(s string) (addrRange, error) {
+	str := strings.TrimSpace(s)
+
+	first, last, isRange := strings.Cut(str, "-")
+	start, err := netip.ParseAddr(first)
+	if err != nil {
+		return addrRange{}, fmt.Errorf("%s is not a valid IP", str)
+	}
+	start = start.Unmap()
+	if !isRange {
+		return addrRange{start: start, end: start}, nil
+	}
+
+	end, err := netip.ParseAddr(last)
+	if err != nil {
+		// The end of the range can be provided as the last octet of the address
+		num, nerr := strconv.Atoi(last)
+		if nerr != nil || num < 0 || num > 255 {
+			return addrRange{}, fmt.Errorf("%s is not a valid IP range", str)
+		}
+
+		b := start.AsSlice()
+		b[len(b)-1] = byte(num)
+		end, _ = netip.AddrFromSlice(b)
+	}
+	end = end.Unmap()
+
+	if start.Is4() != end.Is4() || start.Compare(end) > 0 {
+		return addrRange{}, fmt.Errorf("%s is not a valid IP range", str)
+	}
+	return addrRange{start: start, end: end}, nil
+}
+
+// intersectRanges returns the address ranges found in both sets of merged ranges.
+func intersectRanges(a, b []addrRange) []addrRange {
+	var results []addrRange
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start, end := a[i].start, a[i].end
+		if b[j].start.Compare(start) > 0 {
+			start = b[j].start
+		}
+		if b[j].end.Compare(end) < 0 {
+			end = b[j].end
+		}
+		if start.Compare(end) <= 0 {
+			results = append(results, addrRange{start: start, end: end})
+		}
+
+		if a[i].end.Compare(b[j].end) < 0 {
+			i++
+		} else {
+			j++
+		}
+	}
+	return results
+}
+
+// subtractRanges returns the addresses in the merged ranges of a that are not found in the merged ranges of b.
+func subtractRanges(a, b []addrRange) []addrRange {
+	var results []addrRange
+
+	for _, r := range a {
+		cur := r
+		remaining := true
+
+		for _, x := range b {
+			if x.end.Compare(cur.start) < 0 {
+				continue
+			}
+			if x.start.Compare(cur.end) > 0 {
+				break
+			}
+			if x.start.Compare(cur.start) > 0 {
+				results = append(results, addrRange{start: cur.start, end: x.start.Prev()})
+			}
+
+			next := x.end.Next()
+			if !next.IsValid() || next.Compare(cur.end) > 0 || next.Is4() != cur.end.Is4() {
+				remaining = false
+				break
+			}
+			cur.start = next
+		}
+
+		if remaining {
+			results = append(results, cur)
+		}
+	}
+	return results
+}
@@ -0,0 +1,84 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestAddrRangePrefixes(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		want  []string
+	}{
+		{
+			name:  "single address",
+			start: "192.0.2.1",
+			end:   "192.0.2.1",
+			want:  []string{"192.0.2.1/32"},
+		},
+		{
+			name:  "aligned block",
+			start: "192.0.2.0",
+			end:   "192.0.2.255",
+			want:  []string{"192.0.2.0/24"},
+		},
+		{
+			name:  "unaligned range",
+			start: "192.0.2.3",
+			end:   "192.0.2.8",
+			want:  []string{"192.0.2.3/32", "192.0.2.4/30", "192.0.2.8/32"},
+		},
+		{
+			name:  "entire address space",
+			start: "0.0.0.0",
+			end:   "255.255.255.255",
+			want:  []string{"0.0.0.0/0"},
+		},
+		{
+			name:  "ipv6 range",
+			start: "2001:db8::",
+			end:   "2001:db8::ffff",
+			want:  []string{"2001:db8::/112"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := addrRange{start: netip.MustParseAddr(tt.start), end: netip.MustParseAddr(tt.end)}
+
+			var got []string
+			for _, p := range r.prefixes() {
+				got = append(got, p.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prefixes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	ranges := []addrRange{
+		prefixRange(netip.MustParsePrefix("192.0.2.128/25")),
+		prefixRange(netip.MustParsePrefix("192.0.2.0/25")),
+		prefixRange(netip.MustParsePrefix("192.0.2.64/26")),
+		prefixRange(netip.MustParsePrefix("2001:db8::/64")),
+		{start: netip.MustParseAddr("198.51.100.9"), end: netip.MustParseAddr("198.51.100.9")},
+	}
+
+	var got []string
+	for _, r := range mergeRanges(ranges) {
+		got = append(got, r.String())
+	}
+
+	want := []string{"192.0.2.0-192.0.2.255", "198.51.100.9", "2001:db8::-2001:db8::ffff:ffff:ffff:ffff"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeRanges() = %v, want %v", got, want)
+	}
+}
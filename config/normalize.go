@@ -0,0 +1,87 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// The IDNA profile used to convert names into the lowercase A-label form. The strict domain name rules
+// are turned off to permit underscores, which commonly appear in DNS names, such as service records,
+// so the characters of the resulting labels are checked by checkLabels.
+var domainProfile = idna.New(
+	idna.MapForLookup(),
+	idna.StrictDomainName(false),
+	idna.VerifyDNSLength(true),
+	idna.BidiRule(),
+)
+
+// NormalizeDomain converts the DNS name provided into the lowercase A-label (punycode) form
+// and removes a trailing dot. An error is returned when the name contains an invalid label,
+// such as a label holding characters other than letters, digits, hyphens and underscores.
+func NormalizeDomain(name string) (string, error) {
+	n := strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if n == "" {
+		return "", fmt.Errorf("the DNS name is empty")
+	}
+
+	ascii, err := domainProfile.ToASCII(n)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a valid DNS name: %v", name, err)
+	}
+
+	ascii = strings.ToLower(ascii)
+	if err := checkLabels(ascii); err != nil {
+		return "", fmt.Errorf("%s is not a valid DNS name: %v", name, err)
+	}
+	return ascii, nil
+}
+
+// checkLabels returns an error naming the first label of the A-label form name that is empty, longer
+// than 63 characters, or holds a character other than a letter, digit, hyphen or underscore.
+func checkLabels(name string) error {
+	for _, label := range strings.Split(name, ".") {
+		if l := len(label); l < 1 || l > 63 {
+			return fmt.Errorf("the label %q must be 1 to 63 characters long", label)
+		}
+
+		for _, r := range label {
+			if r != '-' && r != '_' && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+				return fmt.Errorf("the label %q contains the invalid character %q", label, r)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeQuery normalizes a name that is checked against the scope. Names that fail the
+// IDNA validation are lower-cased, so they are still compared with the scope entries.
+func normalizeQuery(name string) string {
+	if n, err := NormalizeDomain(name); err == nil {
+		return n
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// normalizeDomains normalizes each name in the list and removes the duplicates, while preserving the order.
+func normalizeDomains(names []string) ([]string, error) {
+	var results []string
+	seen := make(map[string]struct{}, len(names))
+
+	for _, name := range names {
+		n, err := NormalizeDomain(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, found := seen[n]; !found {
+			seen[n] = struct{}{}
+			results = append(results, n)
+		}
+	}
+	return results, nil
+}
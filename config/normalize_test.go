@@ -0,0 +1,188 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		domain  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "mixed case",
+			domain: "Example.COM",
+			want:   "example.com",
+		},
+		{
+			name:   "trailing dot",
+			domain: " www.example.com. ",
+			want:   "www.example.com",
+		},
+		{
+			name:   "unicode labels",
+			domain: "Bücher.de",
+			want:   "xn--bcher-kva.de",
+		},
+		{
+			name:   "punycode labels",
+			domain: "XN--BCHER-KVA.de",
+			want:   "xn--bcher-kva.de",
+		},
+		{
+			name:   "underscore label",
+			domain: "_dmarc.example.com",
+			want:   "_dmarc.example.com",
+		},
+		{
+			name:    "empty",
+			domain:  " . ",
+			wantErr: true,
+		},
+		{
+			name:    "empty label",
+			domain:  "www..example.com",
+			wantErr: true,
+		},
+		{
+			name:    "leading hyphen",
+			domain:  "-www.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "space in a label",
+			domain:  "exa mple.com",
+			wantErr: true,
+		},
+		{
+			name:    "punctuation in a label",
+			domain:  "ex!ample.com",
+			wantErr: true,
+		},
+		{
+			name:    "path separator",
+			domain:  "foo/bar.com",
+			wantErr: true,
+		},
+		{
+			name:    "wildcard label",
+			domain:  "*.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "label too long",
+			domain:  strings.Repeat("a", 64) + ".example.com",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeDomain(tt.domain)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NormalizeDomain() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeDomain() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NormalizeDomain("ex!ample.com"); err == nil || !strings.Contains(err.Error(), `"ex!ample"`) {
+		t.Errorf("NormalizeDomain() error does not name the invalid label: %v", err)
+	}
+}
+
+func TestScopeDomainNormalization(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - Example.COM.
+    - bücher.de
+  blacklist:
+    - Staging.Example.com`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "www.example.com", want: "example.com"},
+		{name: "WWW.EXAMPLE.COM.", want: "example.com"},
+		{name: "shop.xn--bcher-kva.de", want: "xn--bcher-kva.de"},
+		{name: "shop.bücher.de", want: "xn--bcher-kva.de"},
+		{name: "buecher.de", want: ""},
+	}
+	for _, tt := range tests {
+		if got := c.WhichDomain(tt.name); got != tt.want {
+			t.Errorf("WhichDomain(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if !c.Blacklisted("api.staging.example.com") {
+		t.Errorf("the normalized blacklist entry was not matched")
+	}
+	if err := c.AddDomainE("ünïcödé.example"); err != nil {
+		t.Errorf("AddDomainE() failed to add a unicode name: %v", err)
+	}
+	if c.DomainRegex("ÜNÏCÖDÉ.example") == nil {
+		t.Errorf("DomainRegex() did not find the normalized domain name")
+	}
+	if err := c.AddDomainE("-bad.example.com"); err == nil {
+		t.Errorf("AddDomainE() accepted an invalid label")
+	}
+	if err := c.BlacklistSubdomainE(strings.Repeat("a", 64) + ".example.com"); err == nil {
+		t.Errorf("BlacklistSubdomainE() accepted an invalid label")
+	}
+}
+
+func TestAddDomainSkipsInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewConfig()
+	c.Log = log.New(&buf, "", 0)
+
+	c.AddDomains("owasp.org", "foo/bar.com")
+	c.BlacklistSubdomain("exa mple.owasp.org")
+	if !reflect.DeepEqual(c.Domains(), []string{"owasp.org"}) {
+		t.Errorf("AddDomains() = %v, want [owasp.org]", c.Domains())
+	}
+	if len(c.Scope.Blacklist) != 0 {
+		t.Errorf("BlacklistSubdomain() added an invalid name: %v", c.Scope.Blacklist)
+	}
+	if out := buf.String(); !strings.Contains(out, `"foo/bar"`) || !strings.Contains(out, `"exa mple"`) {
+		t.Errorf("the invalid names were not logged: %s", out)
+	}
+	if err := c.AddDomainsE("owasp.org", "foo/bar.com"); err == nil {
+		t.Errorf("AddDomainsE() did not report the invalid name")
+	}
+}
+
+func TestScopeDomainNormalizationInvalid(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+seed:
+  domains:
+    - www..example.com`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err == nil {
+		t.Errorf("an invalid seed domain name was accepted")
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	minPortNumber = 1
+	maxPortNumber = 65535
+	// maxExpandedPorts is the largest port range that is expanded into the Ports of the scope
+	maxExpandedPorts = 256
+)
+
+// DefaultPorts is the list of ports used when the configuration does not specify any.
+var DefaultPorts = []int{80, 443}
+
+// PortSets maps the named port sets that can be used in the scope ports section
+// to the port specifications they expand to.
+var PortSets = map[string][]string{
+	"web": {"80/tcp", "443/tcp", "8000/tcp", "8008/tcp", "8080/tcp", "8443/tcp", "8888/tcp"},
+	"top100": {
+		"7/tcp", "9/tcp", "13/tcp", "21-23/tcp", "25-26/tcp", "37/tcp", "53/tcp", "79-81/tcp",
+		"88/tcp", "106/tcp", "110-111/tcp", "113/tcp", "119/tcp", "135/tcp", "139/tcp",
+		"143-144/tcp", "179/tcp", "199/tcp", "389/tcp", "427/tcp", "443-445/tcp", "465/tcp",
+		"513-515/tcp", "543-544/tcp", "548/tcp", "554/tcp", "587/tcp", "631/tcp", "646/tcp",
+		"873/tcp", "990/tcp", "993/tcp", "995/tcp", "1025-1029/tcp", "1110/tcp", "1433/tcp",
+		"1720/tcp", "1723/tcp", "1755/tcp", "1900/tcp", "2000-2001/tcp", "2049/tcp", "2121/tcp",
+		"2717/tcp", "3000/tcp", "3128/tcp", "3306/tcp", "3389/tcp", "3986/tcp", "4899/tcp",
+		"5000/tcp", "5009/tcp", "5051/tcp", "5060/tcp", "5101/tcp", "5190/tcp", "5357/tcp",
+		"5432/tcp", "5631/tcp", "5666/tcp", "5800/tcp", "5900/tcp", "6000-6001/tcp", "6646/tcp",
+		"7070/tcp", "8000/tcp", "8008-8009/tcp", "8080-8081/tcp", "8443/tcp", "8888/tcp",
+		"9100/tcp", "9999-10000/tcp", "32768/tcp", "49152-49157/tcp",
+	},
+}
+
+// PortRange represents a contiguous range of ports in scope for an optional protocol.
+type PortRange struct {
+	// The transport protocol (tcp or udp). An empty string matches any protocol
+	Protocol string `yaml:"-" json:"protocol,omitempty"`
+
+	// The first port number in the range
+	Start int `yaml:"-" json:"start"`
+
+	// The last port number in the range
+	End int `yaml:"-" json:"end"`
+}
+
+// Contains returns true if the protocol and port provided fall within the range.
+func (r *PortRange) Contains(proto string, port int) bool {
+	if port < r.Start || port > r.End {
+		return false
+	}
+
+	p := strings.ToLower(strings.TrimSpace(proto))
+	return r.Protocol == "" || p == "" || r.Protocol == p
+}
+
+// String returns the port range using the same notation accepted in the configuration.
+func (r *PortRange) String() string {
+	s := strconv.Itoa(r.Start)
+	if r.End != r.Start {
+		s += "-" + strconv.Itoa(r.End)
+	}
+	if r.Protocol != "" {
+		s += "/" + r.Protocol
+	}
+	return s
+}
+
+// ParsePorts parses a port specification, such as "443", "8000-8100", "53/udp" or a
+// named set like "web", into the port ranges it represents.
+func ParsePorts(spec string) ([]*PortRange, error) {
+	s := strings.ToLower(strings.TrimSpace(spec))
+	if s == "" {
+		return nil, fmt.Errorf("the port specification is empty")
+	}
+
+	if set, found := PortSets[s]; found {
+		var ranges []*PortRange
+
+		for _, entry := range set {
+			r, err := parsePortRange(entry)
+			if err != nil {
+				return nil, fmt.Errorf("the %s port set is invalid: %v", s, err)
+			}
+			ranges = append(ranges, r)
+		}
+		return ranges, nil
+	}
+
+	r, err := parsePortRange(s)
+	if err != nil {
+		return nil, err
+	}
+	return []*PortRange{r}, nil
+}
+
+func parsePortRange(spec string) (*PortRange, error) {
+	r := &PortRange{}
+	s := spec
+
+	if before, after, found := strings.Cut(s, "/"); found {
+		switch after {
+		case "tcp", "udp":
+			r.Protocol = after
+		default:
+			return nil, fmt.Errorf("%s has an unsupported protocol: %s", spec, after)
+		}
+		s = before
+	}
+
+	start, end, isRange := strings.Cut(s, "-")
+	if !isRange {
+		end = start
+	}
+
+	var err error
+	if r.Start, err = parsePortNumber(start); err != nil {
+		return nil, fmt.Errorf("invalid port specification %q: %v", spec, err)
+	}
+	if r.End, err = parsePortNumber(end); err != nil {
+		return nil, fmt.Errorf("invalid port specification %q: %v", spec, err)
+	}
+	if r.Start > r.End {
+		return nil, fmt.Errorf("%s is not a valid port range", spec)
+	}
+	return r, nil
+}
+
+func parsePortNumber(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid port number", s)
+	}
+	if port < minPortNumber || port > maxPortNumber {
+		return 0, fmt.Errorf("port %d is not within %d-%d", port, minPortNumber, maxPortNumber)
+	}
+	return port, nil
+}
+
+// populatePorts parses the port specifications into port ranges and the flat list of port numbers.
+// Ranges larger than maxExpandedPorts are only kept in PortRanges, which IsPortInScope checks.
+func (s *Scope) populatePorts() error {
+	// Port numbers assigned directly, without any specifications, are kept as provided
+	if len(s.PortStrings) == 0 {
+		for _, port := range s.Ports {
+			s.PortStrings = append(s.PortStrings, strconv.Itoa(port))
+		}
+	}
+
+	var ranges []*PortRange
+	for _, spec := range s.PortStrings {
+		r, err := ParsePorts(spec)
+		if err != nil {
+			return err
+		}
+		ranges = append(ranges, r...)
+	}
+
+	s.PortRanges = ranges
+	s.Ports = portNumbers(ranges)
+	return nil
+}
+
+// MarshalJSON writes the port specifications next to the port numbers, so the protocols and the ranges
+// larger than maxExpandedPorts, which are not held in Ports, are preserved.
+func (s Scope) MarshalJSON() ([]byte, error) {
+	type plain Scope
+
+	if len(s.PortRanges) > 0 {
+		s.PortStrings = s.portSpecs()
+	}
+
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(plain(s)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// portSpecs returns the port specifications of the scope. When the scope was not loaded from the
+// specifications, they are built from the port ranges, and otherwise from the port numbers.
+func (s *Scope) portSpecs() []string {
+	if len(s.PortStrings) > 0 {
+		return s.PortStrings
+	}
+
+	var specs []string
+	if len(s.PortRanges) > 0 {
+		for _, r := range s.PortRanges {
+			specs = append(specs, r.String())
+		}
+		return specs
+	}
+	for _, port := range s.Ports {
+		specs = append(specs, strconv.Itoa(port))
+	}
+	return specs
+}
+
+// portNumbers returns the sorted and deduplicated port numbers covered by the ranges, skipping the
+// ranges of more than maxExpandedPorts ports, so that a range such as 1-65535 is not expanded in memory.
+func portNumbers(ranges []*PortRange) []int {
+	var ports []int
+	for _, r := range ranges {
+		if r.End-r.Start >= maxExpandedPorts {
+			continue
+		}
+		for p := r.Start; p <= r.End; p++ {
+			ports = append(ports, p)
+		}
+	}
+
+	sort.Ints(ports)
+	return slices.Compact(ports)
+}
+
+// IsPortInScope returns true if the port, reached using the protocol provided, is in scope.
+// An empty protocol matches any port range with the port number.
+func (c *Config) IsPortInScope(proto string, port int) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.Scope == nil {
+		return false
+	}
+
+	if len(c.Scope.PortRanges) == 0 {
+		for _, p := range c.Scope.Ports {
+			if p == port {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, r := range c.Scope.PortRanges {
+		if r.Contains(proto, port) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []*PortRange
+		wantErr bool
+	}{
+		{
+			name: "single port",
+			spec: "443",
+			want: []*PortRange{{Start: 443, End: 443}},
+		},
+		{
+			name: "port range",
+			spec: "8000-8100",
+			want: []*PortRange{{Start: 8000, End: 8100}},
+		},
+		{
+			name: "port with protocol",
+			spec: "53/UDP",
+			want: []*PortRange{{Protocol: "udp", Start: 53, End: 53}},
+		},
+		{
+			name: "range with protocol",
+			spec: "1-1024/tcp",
+			want: []*PortRange{{Protocol: "tcp", Start: 1, End: 1024}},
+		},
+		{
+			name:    "port zero",
+			spec:    "0",
+			wantErr: true,
+		},
+		{
+			name:    "port too large",
+			spec:    "65536",
+			wantErr: true,
+		},
+		{
+			name:    "inverted range",
+			spec:    "8100-8000",
+			wantErr: true,
+		},
+		{
+			name:    "unknown protocol",
+			spec:    "80/icmp",
+			wantErr: true,
+		},
+		{
+			name:    "unknown port set",
+			spec:    "mail",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			spec:    " ",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePorts(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePorts() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePorts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortSets(t *testing.T) {
+	for name := range PortSets {
+		ranges, err := ParsePorts(name)
+		if err != nil {
+			t.Errorf("the %s port set failed to parse: %v", name, err)
+			continue
+		}
+		if name == "top100" {
+			if n := len(portNumbers(ranges)); n != 100 {
+				t.Errorf("the top100 port set contains %d ports", n)
+			}
+		}
+	}
+}
+
+func TestIsPortInScope(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  ports:
+    - 80
+    - 8000-8100
+    - 443/tcp
+    - 53/udp
+    - web`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatalf("failed to load the scope: %v", err)
+	}
+
+	tests := []struct {
+		proto string
+		port  int
+		want  bool
+	}{
+		{proto: "tcp", port: 80, want: true},
+		{proto: "udp", port: 80, want: true},
+		{proto: "tcp", port: 8050, want: true},
+		{proto: "tcp", port: 8101, want: false},
+		{proto: "tcp", port: 443, want: true},
+		{proto: "udp", port: 443, want: false},
+		{proto: "udp", port: 53, want: true},
+		{proto: "tcp", port: 53, want: false},
+		{proto: "", port: 53, want: true},
+		{proto: "TCP", port: 8443, want: true},
+		{proto: "tcp", port: 22, want: false},
+	}
+	for _, tt := range tests {
+		if got := c.IsPortInScope(tt.proto, tt.port); got != tt.want {
+			t.Errorf("IsPortInScope(%s, %d) = %v, want %v", tt.proto, tt.port, got, tt.want)
+		}
+	}
+
+	if c.Scope.Ports[0] != 53 || c.Scope.Ports[len(c.Scope.Ports)-1] != 8888 {
+		t.Errorf("the scope ports were not expanded and sorted: %v", c.Scope.Ports)
+	}
+}
+
+func TestIsPortInScopeDefaults(t *testing.T) {
+	c := NewConfig()
+
+	for _, port := range DefaultPorts {
+		if !c.IsPortInScope("tcp", port) {
+			t.Errorf("default port %d is not in scope", port)
+		}
+	}
+	if c.IsPortInScope("tcp", 8080) {
+		t.Errorf("port 8080 is in scope by default")
+	}
+}
+
+func TestLoadScopePortsInvalid(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - owasp.org
+  ports:
+    - 70000`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err == nil {
+		t.Errorf("an out of range port was accepted")
+	}
+}
+
+func TestLoadScopeLargePortRange(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - owasp.org
+  ports:
+    - 1-65535/tcp
+    - 53/udp`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatalf("failed to load the scope: %v", err)
+	}
+
+	if !reflect.DeepEqual(c.Scope.Ports, []int{53}) {
+		t.Errorf("the large port range was expanded into the scope ports: %d ports", len(c.Scope.Ports))
+	}
+	if len(c.Scope.PortRanges) != 2 {
+		t.Errorf("expected two port ranges, got %v", c.Scope.PortRanges)
+	}
+	if !c.IsPortInScope("tcp", 65535) || !c.IsPortInScope("udp", 53) || c.IsPortInScope("udp", 54) {
+		t.Errorf("the large port range was not checked")
+	}
+}
+
+func TestMarshalScopePorts(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  ports:
+    - 1-65535/tcp
+    - 53/udp`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatalf("failed to load the scope: %v", err)
+	}
+
+	data, err := json.Marshal(c.Scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Scope
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1-65535/tcp", "53/udp"}; !reflect.DeepEqual(fromJSON.PortStrings, want) {
+		t.Errorf("the JSON port specifications = %v, want %v", fromJSON.PortStrings, want)
+	}
+
+	// A scope built without the port specifications keeps the ports in both formats
+	built := &Scope{Ports: []int{8080}}
+	out, err := yaml.Marshal(built)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromYAML Scope
+	if err := yaml.Unmarshal(out, &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYAML.PortStrings, []string{"8080"}) {
+		t.Errorf("the YAML port specifications = %v, want [8080]", fromYAML.PortStrings)
+	}
+
+	built = &Scope{PortRanges: []*PortRange{{Protocol: "udp", Start: 500, End: 1500}}}
+	if data, err = json.Marshal(built); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"port_specs":["500-1500/udp"]`) {
+		t.Errorf("the JSON output did not contain the port range: %s", data)
+	}
+}
+
+func TestParsePortsErrorSpec(t *testing.T) {
+	for _, spec := range []string{"/tcp", "a-80/udp", "80-b"} {
+		if _, err := ParsePorts(spec); err == nil || !strings.Contains(err.Error(), spec) {
+			t.Errorf("ParsePorts(%s) error = %v, want the specification in the error", spec, err)
+		}
+	}
+}
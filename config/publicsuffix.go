@@ -0,0 +1,122 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// PublicSuffixRefuse causes public suffixes to be rejected when added as root domains.
+	PublicSuffixRefuse = "refuse"
+	// PublicSuffixWarn causes public suffixes to be accepted as root domains after logging a warning.
+	PublicSuffixWarn = "warn"
+)
+
+// loadPublicSuffixSettings reads the public suffix options and checks the seed and scope root domains.
+func (c *Config) loadPublicSuffixSettings(cfg *Config) error {
+	if raw, ok := c.Options["public_suffix_roots"]; ok {
+		policy, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("failed to parse public_suffix_roots setting, value is not a string")
+		}
+
+		switch p := strings.ToLower(strings.TrimSpace(policy)); p {
+		case PublicSuffixRefuse, PublicSuffixWarn:
+			c.PublicSuffixRoots = p
+		default:
+			return fmt.Errorf("public_suffix_roots must be %s or %s, got %s", PublicSuffixRefuse, PublicSuffixWarn, policy)
+		}
+	}
+
+	if raw, ok := c.Options["registered_domain_roots"]; ok {
+		enabled, ok := raw.(bool)
+		if !ok {
+			return fmt.Errorf("failed to parse registered_domain_roots setting, value is not a boolean")
+		}
+		c.RegisteredDomainRoots = enabled
+	}
+
+	// The seed and scope often list the same domains, which are checked once
+	checked := make(map[string]struct{})
+	for _, s := range []*Scope{c.Seed, c.Scope} {
+		if s == nil {
+			continue
+		}
+		for _, d := range s.Domains {
+			if _, found := checked[d]; found {
+				continue
+			}
+			checked[d] = struct{}{}
+
+			if err := c.checkPublicSuffix(d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkPublicSuffix applies the public suffix policy to the root domain name provided.
+func (c *Config) checkPublicSuffix(domain string) error {
+	if !IsPublicSuffix(domain) {
+		return nil
+	}
+
+	if c.PublicSuffixRoots == PublicSuffixWarn {
+		c.warnf("%s is a public suffix and places every domain registered under it in scope", domain)
+		return nil
+	}
+	return fmt.Errorf("%s is a public suffix and cannot be added as a root domain", domain)
+}
+
+// IsPublicSuffix returns true if the name is a public suffix, such as co.uk or github.io.
+func IsPublicSuffix(name string) bool {
+	n := normalizeQuery(name)
+	if n == "" {
+		return false
+	}
+
+	suffix, icann := publicsuffix.PublicSuffix(n)
+	if suffix != n {
+		return false
+	}
+	// The implicit wildcard rule matches any single label name not found in the list
+	return icann || strings.Contains(suffix, ".")
+}
+
+// RegisteredDomain returns the registered domain (eTLD+1) for the DNS name provided,
+// according to the Public Suffix List. For example, www.example.co.uk returns example.co.uk.
+func RegisteredDomain(name string) (string, error) {
+	n, err := NormalizeDomain(name)
+	if err != nil {
+		return "", err
+	}
+	return publicsuffix.EffectiveTLDPlusOne(n)
+}
+
+// CandidateRoot returns the registered domain of the discovered name when the registered_domain_roots
+// option is enabled, and the registered domain is not already a root domain or blacklisted.
+// Otherwise, an empty string is returned.
+func (c *Config) CandidateRoot(name string) string {
+	if !c.RegisteredDomainRoots {
+		return ""
+	}
+
+	rd, err := RegisteredDomain(name)
+	if err != nil || c.Blacklisted(rd) {
+		return ""
+	}
+
+	for _, d := range c.Domains() {
+		if d == rd {
+			return ""
+		}
+	}
+	return rd
+}
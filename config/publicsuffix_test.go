@@ -0,0 +1,171 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestIsPublicSuffix(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "com", want: true},
+		{name: "co.uk", want: true},
+		{name: "CO.UK.", want: true},
+		{name: "github.io", want: true},
+		{name: "owasp.org", want: false},
+		{name: "example.co.uk", want: false},
+		{name: "owasp.github.io", want: false},
+		{name: "corp.internal", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicSuffix(tt.name); got != tt.want {
+			t.Errorf("IsPublicSuffix(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRegisteredDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "www.example.co.uk", want: "example.co.uk"},
+		{name: "a.b.owasp.org.", want: "owasp.org"},
+		{name: "owasp.github.io", want: "owasp.github.io"},
+		{name: "shop.bücher.de", want: "xn--bcher-kva.de"},
+		{name: "co.uk", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := RegisteredDomain(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("RegisteredDomain(%s) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("RegisteredDomain(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddDomainPublicSuffix(t *testing.T) {
+	c := NewConfig()
+
+	if err := c.AddDomainE("co.uk"); err == nil {
+		t.Errorf("AddDomainE() accepted a public suffix as a root domain")
+	}
+	if err := c.AddDomainE("github.io"); err == nil {
+		t.Errorf("AddDomainE() accepted a private public suffix as a root domain")
+	}
+	if c.IsDomainInScope("example.co.uk") {
+		t.Errorf("the public suffix was added to the scope")
+	}
+
+	var buf bytes.Buffer
+	c.Log = log.New(&buf, "", 0)
+	c.PublicSuffixRoots = PublicSuffixWarn
+	if err := c.AddDomainE("co.uk"); err != nil {
+		t.Errorf("AddDomainE() refused a public suffix with the warn policy: %v", err)
+	}
+	if !strings.Contains(buf.String(), "co.uk is a public suffix") {
+		t.Errorf("AddDomainE() did not log a warning for the public suffix")
+	}
+}
+
+func TestLoadPublicSuffixSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     string
+		wantErr bool
+	}{
+		{
+			name: "refused by default",
+			cfg: `
+scope:
+  domains:
+    - co.uk`,
+			wantErr: true,
+		},
+		{
+			name: "refused in the seed",
+			cfg: `
+seed:
+  domains:
+    - github.io`,
+			wantErr: true,
+		},
+		{
+			name: "accepted with a warning",
+			cfg: `
+scope:
+  domains:
+    - co.uk
+options:
+  public_suffix_roots: warn`,
+		},
+		{
+			name: "invalid policy",
+			cfg: `
+options:
+  public_suffix_roots: ignore`,
+			wantErr: true,
+		},
+		{
+			name: "invalid registered domain roots",
+			cfg: `
+options:
+  registered_domain_roots: sometimes`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			if err := yaml.Unmarshal([]byte(tt.cfg), c); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.loadSeedandScopeSettings(); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.loadPublicSuffixSettings(c); (err != nil) != tt.wantErr {
+				t.Errorf("loadPublicSuffixSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCandidateRoot(t *testing.T) {
+	c := NewConfig()
+	c.AddDomain("owasp.org")
+	c.BlacklistSubdomain("evil.com")
+
+	if got := c.CandidateRoot("www.example.com"); got != "" {
+		t.Errorf("CandidateRoot() = %s without the option enabled", got)
+	}
+
+	c.RegisteredDomainRoots = true
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "www.example.co.uk", want: "example.co.uk"},
+		{name: "www.owasp.org", want: ""},
+		{name: "api.evil.com", want: ""},
+		{name: "co.uk", want: ""},
+	}
+	for _, tt := range tests {
+		if got := c.CandidateRoot(tt.name); got != tt.want {
+			t.Errorf("CandidateRoot(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+const (
+	// ReservedRangesWarn causes reserved address space in scope to be accepted after logging a warning.
+	ReservedRangesWarn = "warn"
+	// ReservedRangesBlock causes reserved address space in scope to be rejected.
+	ReservedRangesBlock = "block"
+)
+
+// SpecialPurposeRange represents an entry of the IANA IPv4 and IPv6 Special-Purpose Address Registries.
+type SpecialPurposeRange struct {
+	Prefix netip.Prefix
+	Name   string
+	RFC    string
+}
+
+// SpecialPurposeRanges contains the entries of the IANA Special-Purpose Address Registries that are not
+// globally reachable, along with the multicast address space. Scope that includes these ranges usually
+// indicates a misconfiguration, such as an internal network or a documentation example.
+var SpecialPurposeRanges = []SpecialPurposeRange{
+	{Prefix: netip.MustParsePrefix("0.0.0.0/8"), Name: "This network", RFC: "RFC 791"},
+	{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Name: "Private-Use", RFC: "RFC 1918"},
+	{Prefix: netip.MustParsePrefix("100.64.0.0/10"), Name: "Shared Address Space", RFC: "RFC 6598"},
+	{Prefix: netip.MustParsePrefix("127.0.0.0/8"), Name: "Loopback", RFC: "RFC 1122"},
+	{Prefix: netip.MustParsePrefix("169.254.0.0/16"), Name: "Link Local", RFC: "RFC 3927"},
+	{Prefix: netip.MustParsePrefix("172.16.0.0/12"), Name: "Private-Use", RFC: "RFC 1918"},
+	{Prefix: netip.MustParsePrefix("192.0.0.0/24"), Name: "IETF Protocol Assignments", RFC: "RFC 6890"},
+	{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Name: "Documentation (TEST-NET-1)", RFC: "RFC 5737"},
+	{Prefix: netip.MustParsePrefix("192.88.99.0/24"), Name: "Deprecated (6to4 Relay Anycast)", RFC: "RFC 7526"},
+	{Prefix: netip.MustParsePrefix("192.168.0.0/16"), Name: "Private-Use", RFC: "RFC 1918"},
+	{Prefix: netip.MustParsePrefix("198.18.0.0/15"), Name: "Benchmarking", RFC: "RFC 2544"},
+	{Prefix: netip.MustParsePrefix("198.51.100.0/24"), Name: "Documentation (TEST-NET-2)", RFC: "RFC 5737"},
+	{Prefix: netip.MustParsePrefix("203.0.113.0/24"), Name: "Documentation (TEST-NET-3)", RFC: "RFC 5737"},
+	{Prefix: netip.MustParsePrefix("224.0.0.0/4"), Name: "Multicast", RFC: "RFC 5771"},
+	{Prefix: netip.MustParsePrefix("240.0.0.0/4"), Name: "Reserved", RFC: "RFC 1112"},
+	{Prefix: netip.MustParsePrefix("255.255.255.255/32"), Name: "Limited Broadcast", RFC: "RFC 919"},
+	{Prefix: netip.MustParsePrefix("::/128"), Name: "Unspecified Address", RFC: "RFC 4291"},
+	{Prefix: netip.MustParsePrefix("::1/128"), Name: "Loopback Address", RFC: "RFC 4291"},
+	{Prefix: netip.MustParsePrefix("::ffff:0:0/96"), Name: "IPv4-mapped Address", RFC: "RFC 4291"},
+	{Prefix: netip.MustParsePrefix("64:ff9b:1::/48"), Name: "IPv4-IPv6 Translation", RFC: "RFC 8215"},
+	{Prefix: netip.MustParsePrefix("100::/64"), Name: "Discard-Only Address Block", RFC: "RFC 6666"},
+	{Prefix: netip.MustParsePrefix("2001:2::/48"), Name: "Benchmarking", RFC: "RFC 5180"},
+	{Prefix: netip.MustParsePrefix("2001:10::/28"), Name: "Deprecated (ORCHID)", RFC: "RFC 4843"},
+	{Prefix: netip.MustParsePrefix("2001:db8::/32"), Name: "Documentation", RFC: "RFC 3849"},
+	{Prefix: netip.MustParsePrefix("3fff::/20"), Name: "Documentation", RFC: "RFC 9637"},
+	{Prefix: netip.MustParsePrefix("5f00::/16"), Name: "Segment Routing (SRv6) SIDs", RFC: "RFC 9602"},
+	{Prefix: netip.MustParsePrefix("fc00::/7"), Name: "Unique-Local", RFC: "RFC 4193"},
+	{Prefix: netip.MustParsePrefix("fe80::/10"), Name: "Link-Local Unicast", RFC: "RFC 4291"},
+	{Prefix: netip.MustParsePrefix("ff00::/8"), Name: "Multicast", RFC: "RFC 4291"},
+}
+
+// loadReservedRangeSettings reads the reserved address space options and checks the seed and scope networks.
+func (c *Config) loadReservedRangeSettings(cfg *Config) error {
+	if raw, ok := c.Options["reserved_ranges"]; ok {
+		policy, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("failed to parse reserved_ranges setting, value is not a string")
+		}
+
+		switch p := strings.ToLower(strings.TrimSpace(policy)); p {
+		case ReservedRangesWarn, ReservedRangesBlock:
+			c.ReservedRanges = p
+		default:
+			return fmt.Errorf("reserved_ranges must be %s or %s, got %s", ReservedRangesWarn, ReservedRangesBlock, policy)
+		}
+	}
+
+	var err error
+	if c.ReservedExceptions, err = c.prefixListOption("reserved_range_exceptions"); err != nil {
+		return err
+	}
+	if c.SharedRanges, err = c.prefixListOption("shared_ranges"); err != nil {
+		return err
+	}
+
+	// The seed and scope often list the same networks, which are merged to be checked once
+	var ranges []addrRange
+	for _, s := range []*Scope{c.Seed, c.Scope} {
+		if s == nil {
+			continue
+		}
+
+		ranges = append(ranges, networkRanges(s.CIDRs, nil)...)
+		for _, ip := range s.IP {
+			if r, err := parseAddrRange(ip); err == nil {
+				ranges = append(ranges, r)
+			}
+		}
+	}
+
+	var errs []error
+	for _, r := range mergeRanges(ranges) {
+		if err := c.checkReservedRange(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// prefixListOption parses the list of addresses and CIDRs found in the option.
+func (c *Config) prefixListOption(key string) ([]netip.Prefix, error) {
+	raw, ok := c.Options[key]
+	if !ok {
+		return nil, nil
+	}
+
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s section is not a list", key)
+	}
+
+	var prefixes []netip.Prefix
+	for _, v := range list {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s entry %v is not a string", key, v)
+		}
+
+		ipnet, err := parseExclusion(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry: %v", key, err)
+		}
+		if p, ok := ipNetToPrefix(ipnet); ok {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes, nil
+}
+
+// checkReservedRange applies the reserved address space policy to the range of addresses in scope.
+func (c *Config) checkReservedRange(r addrRange) error {
+	overlaps := c.ReservedOverlaps(r.start, r.end)
+	if len(overlaps) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%s includes reserved address space: %s", r.String(), strings.Join(overlaps, ", "))
+	if c.ReservedRanges == ReservedRangesBlock {
+		return errors.New(msg)
+	}
+
+	c.warnf("%s", msg)
+	return nil
+}
+
+// ReservedOverlaps returns a description of the special-purpose and shared ranges that overlap with the
+// range of addresses from start to end, ignoring the addresses covered by the reserved range exceptions.
+func (c *Config) ReservedOverlaps(start, end netip.Addr) []string {
+	r := []addrRange{{start: start.Unmap(), end: end.Unmap()}}
+
+	var exceptions []addrRange
+	for _, p := range c.ReservedExceptions {
+		exceptions = append(exceptions, prefixRange(p))
+	}
+	exceptions = mergeRanges(exceptions)
+
+	var results []string
+	for _, spr := range SpecialPurposeRanges {
+		if len(subtractRanges(intersectRanges(r, []addrRange{prefixRange(spr.Prefix)}), exceptions)) > 0 {
+			results = append(results, fmt.Sprintf("%s (%s, %s)", spr.Prefix, spr.Name, spr.RFC))
+		}
+	}
+	for _, p := range c.SharedRanges {
+		if len(subtractRanges(intersectRanges(r, []addrRange{prefixRange(p)}), exceptions)) > 0 {
+			results = append(results, fmt.Sprintf("%s (Shared hosting)", p))
+		}
+	}
+	return results
+}
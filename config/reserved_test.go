@@ -0,0 +1,199 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"log"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestReservedOverlaps(t *testing.T) {
+	c := NewConfig()
+	c.ReservedExceptions = []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}
+	c.SharedRanges = []netip.Prefix{netip.MustParsePrefix("104.16.0.0/13")}
+
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		want  []string
+	}{
+		{name: "public address", start: "8.8.8.8", end: "8.8.8.8"},
+		{name: "private address", start: "192.168.1.1", end: "192.168.1.1", want: []string{"192.168.0.0/16 (Private-Use, RFC 1918)"}},
+		{name: "range spanning two registry entries", start: "198.18.0.0", end: "198.51.100.255", want: []string{
+			"198.18.0.0/15 (Benchmarking, RFC 2544)",
+			"198.51.100.0/24 (Documentation (TEST-NET-2), RFC 5737)",
+		}},
+		{name: "covered by an exception", start: "10.1.2.0", end: "10.1.2.255"},
+		{name: "partially covered by an exception", start: "10.0.255.0", end: "10.1.0.255", want: []string{"10.0.0.0/8 (Private-Use, RFC 1918)"}},
+		{name: "shared range", start: "104.17.1.1", end: "104.17.1.1", want: []string{"104.16.0.0/13 (Shared hosting)"}},
+		{name: "ipv6 documentation", start: "2001:db8::1", end: "2001:db8::1", want: []string{"2001:db8::/32 (Documentation, RFC 3849)"}},
+		{name: "ipv6 global unicast", start: "2606:4700::", end: "2606:4700::ffff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.ReservedOverlaps(netip.MustParseAddr(tt.start), netip.MustParseAddr(tt.end))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReservedOverlaps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadReservedRangeSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     string
+		warning string
+		wantErr bool
+	}{
+		{
+			name: "public networks",
+			cfg: `
+scope:
+  cidrs:
+    - 8.8.8.0/24
+  ips:
+    - 1.1.1.1-10
+options:
+  reserved_ranges: block`,
+		},
+		{
+			name: "warning by default",
+			cfg: `
+scope:
+  cidrs:
+    - 192.168.0.0/24`,
+			warning: "192.168.0.0/16 (Private-Use, RFC 1918)",
+		},
+		{
+			name: "blocked address range",
+			cfg: `
+scope:
+  ips:
+    - 100.64.0.1-20
+options:
+  reserved_ranges: block`,
+			wantErr: true,
+		},
+		{
+			name: "blocked in the seed",
+			cfg: `
+seed:
+  domains:
+    - owasp.org
+  cidrs:
+    - 2001:db8::/48
+options:
+  reserved_ranges: block`,
+			wantErr: true,
+		},
+		{
+			name: "allowed by an exception",
+			cfg: `
+scope:
+  cidrs:
+    - 10.10.0.0/16
+  ips:
+    - 10.20.0.5
+options:
+  reserved_ranges: block
+  reserved_range_exceptions:
+    - 10.0.0.0/8`,
+		},
+		{
+			name: "blocked shared range",
+			cfg: `
+scope:
+  cidrs:
+    - 104.16.1.0/24
+options:
+  reserved_ranges: block
+  shared_ranges:
+    - 104.16.0.0/13`,
+			wantErr: true,
+		},
+		{
+			name: "invalid policy",
+			cfg: `
+options:
+  reserved_ranges: ignore`,
+			wantErr: true,
+		},
+		{
+			name: "invalid exception",
+			cfg: `
+options:
+  reserved_range_exceptions:
+    - 10.0.0.0/33`,
+			wantErr: true,
+		},
+		{
+			name: "shared ranges not a list",
+			cfg: `
+options:
+  shared_ranges: 104.16.0.0/13`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			if err := yaml.Unmarshal([]byte(tt.cfg), c); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.loadSeedandScopeSettings(); err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			c.Log = log.New(&buf, "", 0)
+			if err := c.loadReservedRangeSettings(c); (err != nil) != tt.wantErr {
+				t.Errorf("loadReservedRangeSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.warning != "" && !strings.Contains(buf.String(), tt.warning) {
+				t.Errorf("loadReservedRangeSettings() logged %q, want a warning about %s", buf.String(), tt.warning)
+			}
+			if tt.warning == "" && buf.Len() > 0 && !tt.wantErr {
+				t.Errorf("loadReservedRangeSettings() logged an unexpected warning: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestAddReservedNetworks(t *testing.T) {
+	c := NewConfig()
+	c.ReservedRanges = ReservedRangesBlock
+	c.ReservedExceptions = []netip.Prefix{netip.MustParsePrefix("172.16.5.0/24")}
+
+	if err := c.AddCIDR("10.0.0.0/8"); err == nil {
+		t.Errorf("AddCIDR() accepted a private network with the block policy")
+	}
+	if err := c.AddAddress("fe80::1"); err == nil {
+		t.Errorf("AddAddress() accepted a link-local address with the block policy")
+	}
+	if err := c.AddCIDR("172.16.5.0/25"); err != nil {
+		t.Errorf("AddCIDR() refused a network covered by an exception: %v", err)
+	}
+	if want := []string{"172.16.5.0/25"}; !reflect.DeepEqual(c.Scope.CIDRStrings, want) {
+		t.Errorf("CIDRStrings = %v, want %v", c.Scope.CIDRStrings, want)
+	}
+
+	var buf bytes.Buffer
+	c.Log = log.New(&buf, "", 0)
+	c.ReservedRanges = ReservedRangesWarn
+	if err := c.AddAddress("127.0.0.1"); err != nil {
+		t.Errorf("AddAddress() refused a loopback address with the warn policy: %v", err)
+	}
+	if !strings.Contains(buf.String(), "127.0.0.0/8 (Loopback, RFC 1122)") {
+		t.Errorf("AddAddress() did not log a warning for the loopback address")
+	}
+}
@@ -5,44 +5,64 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/caffix/stringset"
 	amassnet "github.com/owasp-amass/amass/v4/net"
-	"github.com/owasp-amass/amass/v4/net/dns"
 )
 
 func (c *Config) loadSeedandScopeSettings() error {
-	if c.Seed == nil || c.Seed.isScopeEmpty(false) {
-		if c.Scope == nil {
-			return fmt.Errorf("config seed and scope are not initialized")
-		} else {
-			if err := c.Scope.populate(); err != nil {
-				return err
-			}
-			c.Seed = c.Scope
-			return nil
-		}
-	} else if err := c.Seed.populate(); err != nil {
+	// The indexes are rebuilt from the lists loaded into the scope
+	defer c.ReindexScope()
+
+	if c.Seed == nil && c.Scope == nil {
+		return fmt.Errorf("config seed and scope are not initialized")
+	}
+	if err := c.loadSeedDerivationSettings(); err != nil {
 		return err
 	}
+	if c.Seed == nil {
+		c.Seed = &Scope{}
+	}
+	if c.Scope == nil {
+		c.Scope = &Scope{}
+	}
 
-	if c.Scope == nil || !c.Scope.isScopeEmpty(true) {
-		if err := c.Seed.populate(); err != nil {
-			return err
+	// Replace the file paths in the seed and scope lists with the entries from the files
+	if err := c.expandListFiles(c.Seed); err != nil {
+		return fmt.Errorf("failed to load the seed lists: %w", err)
+	}
+	if err := c.expandListFiles(c.Scope); err != nil {
+		return fmt.Errorf("failed to load the scope lists: %w", err)
+	}
+
+	// Aggregate the networks and report the redundant entries
+	for _, n := range []struct {
+		name  string
+		scope *Scope
+	}{
+		{name: "seed", scope: c.Seed},
+		{name: "scope", scope: c.Scope},
+	} {
+		if redundant := n.scope.aggregateNetworks(); len(redundant) > 0 {
+			c.warnf("redundant %s networks were removed: %s", n.name, strings.Join(redundant, "; "))
 		}
-		c.Scope = c.Seed
-		c.Scope.Ports = []int{80, 443}
-		return nil
-	} else if err := c.Scope.populate(); err != nil {
-		return err
 	}
 
-	return nil
+	c.deriveSeedAndScope()
+	if err := c.Seed.populate(); err != nil {
+		return err
+	}
+	if err := c.Scope.populate(); err != nil {
+		return err
+	}
+	return c.checkSeedWithinScope()
 }
 
 func (s *Scope) isScopeEmpty(scopeSwitch bool) bool {
@@ -65,40 +85,117 @@ func (s *Scope) isScopeEmpty(scopeSwitch bool) bool {
 	}
 	if scopeSwitch && portCheck(s.Ports) {
 		isEmpty = false
-	} else if len(s.Ports) > 0 {
+	} else if len(s.Ports) > 0 || len(s.PortStrings) > 0 {
 		isEmpty = false
 	}
 	if len(s.Blacklist) > 0 {
 		isEmpty = false
 	}
+	if len(s.Exclusions) > 0 {
+		isEmpty = false
+	}
+	if len(s.Organizations) > 0 || len(s.Emails) > 0 || len(s.URLs) > 0 {
+		isEmpty = false
+	}
 
 	return isEmpty
 }
 
 func (s *Scope) populate() error {
+	var err error
+
+	// Normalize the domain names into the lowercase A-label form
+	if s.Domains, err = normalizeDomains(s.Domains); err != nil {
+		return fmt.Errorf("invalid domain name: %v", err)
+	}
+	if err := s.populateDomainOptions(); err != nil {
+		return err
+	}
+	if s.Blacklist, err = normalizeDomains(s.Blacklist); err != nil {
+		return fmt.Errorf("invalid blacklist entry: %v", err)
+	}
+
 	// Convert string CIDRs to net.IP and net.IPNet
 	s.CIDRs = s.toCIDRs(s.CIDRStrings)
 
 	parseIPs := ParseIPs{} // Create a new ParseIPs, which is a []net.IP under the hood
+	s.hostRanges = nil
+	s.ipEntries = nil
 	// Validate IP ranges in c.Scope.IP
 	for _, ipRange := range s.IP {
+		r, err := parseAddrRange(ipRange)
+		if err == nil {
+			s.ipEntries = append(s.ipEntries, ipEntry{entry: ipRange, r: r})
+		}
+		// Large ranges are not expanded, and the addresses are walked lazily by Hosts
+		if err == nil && !r.sizeAtMost(maxExpandedRange) {
+			s.hostRanges = append(s.hostRanges, r)
+			continue
+		}
 		if err := parseIPs.parseRange(ipRange); err != nil {
 			return err
 		}
 	}
 	// append parseIPs (which is a []net.IP) to c.Scope.IP
 	s.Addresses = append(s.Addresses, parseIPs...)
+	// Parse the addresses and CIDRs that are out of scope
+	if err := s.populateExclusions(); err != nil {
+		return err
+	}
+	// Validate the organizations, email addresses and URLs
+	if err := s.populateAssets(); err != nil {
+		return err
+	}
+	// Parse the periods of time when active testing is permitted
+	if err := s.populateWindows(); err != nil {
+		return err
+	}
+	// Validate and expand the port specifications
+	return s.populatePorts()
+}
+
+func (s *Scope) populateExclusions() error {
+	var nets []*net.IPNet
+
+	for _, e := range s.Exclusions {
+		ipnet, err := parseExclusion(e)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, ipnet)
+	}
+
+	s.ExcludedNets = nets
 	return nil
 }
 
-// returns true is ports match default ports (80,443), otherwise return false
+// parseExclusion parses an IP address or CIDR into the network that it represents.
+func parseExclusion(s string) (*net.IPNet, error) {
+	e := strings.TrimSpace(s)
+
+	if _, ipnet, err := net.ParseCIDR(e); err == nil {
+		return ipnet, nil
+	}
+	if ip := net.ParseIP(e); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	return nil, fmt.Errorf("%s is not a valid IP address or CIDR exclusion", e)
+}
+
+// returns true if the ports match the default ports (80,443) in any order, otherwise return false
 func portCheck(ports []int) bool {
-	defaultPorts := []int{80, 443}
-	if len(ports) != len(defaultPorts) {
+	set := make(map[int]struct{}, len(ports))
+	for _, p := range ports {
+		set[p] = struct{}{}
+	}
+	if len(set) != len(DefaultPorts) {
 		return false
 	}
-	for i, v := range ports {
-		if v != defaultPorts[i] {
+	for _, p := range DefaultPorts {
+		if _, found := set[p]; !found {
 			return false
 		}
 	}
@@ -106,44 +203,80 @@ func portCheck(ports []int) bool {
 }
 
 // DomainRegex returns the Regexp object for the domain name identified by the parameter.
+// The expression only matches the names permitted by the exact and max_depth options of the domain.
 func (c *Config) DomainRegex(domain string) *regexp.Regexp {
-	c.Lock()
-	defer c.Unlock()
+	c.RLock()
+	defer c.RUnlock()
 
-	if re, found := c.regexps[domain]; found {
+	if re, found := c.regexps[normalizeQuery(domain)]; found {
 		return re
 	}
 	return nil
 }
 
 // AddDomains appends the domain names provided in the parameter to the list in the configuration.
+// The names that are not valid are logged and skipped.
 func (c *Config) AddDomains(domains ...string) {
 	for _, d := range domains {
 		c.AddDomain(d)
 	}
 }
 
+// AddDomainsE appends the domain names provided in the parameter to the list in the configuration.
+// The names that could not be added are reported in the returned error.
+func (c *Config) AddDomainsE(domains ...string) error {
+	var errs []error
+
+	for _, d := range domains {
+		if err := c.AddDomainE(d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // AddDomain appends the domain name provided in the parameter to the list in the configuration.
+// The name is normalized into the lowercase A-label form, and a name that is not valid is logged and skipped.
 func (c *Config) AddDomain(domain string) {
+	if err := c.AddDomainE(domain); err != nil {
+		c.warnf("the domain name was not added: %v", err)
+	}
+}
+
+// AddDomainE appends the domain name provided in the parameter to the list in the configuration.
+// The name is normalized into the lowercase A-label form before it is added.
+func (c *Config) AddDomainE(domain string) error {
+	added, err := c.addDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	if added != "" {
+		c.publishScopeEvent(DomainAdded, added)
+	}
+	return nil
+}
+
+// addDomain adds the domain name while holding the lock, and returns the name when it was not already in the list.
+func (c *Config) addDomain(domain string) (string, error) {
 	c.Lock()
 	defer c.Unlock()
 
-	// Check that the domain string is not empty
-	d := strings.TrimSpace(domain)
-	if d == "" {
-		return
+	if c.Scope == nil {
+		return "", errNoScope
+	}
+
+	d, err := NormalizeDomain(domain)
+	if err != nil {
+		return "", err
 	}
 	// Check that it is a domain with at least two labels
-	labels := strings.Split(d, ".")
-	if len(labels) < 2 {
-		return
+	if labels := strings.Split(d, "."); len(labels) < 2 {
+		return "", fmt.Errorf("%s must contain at least two labels", d)
 	}
-
-	// Check that none of the labels are empty
-	for _, label := range labels {
-		if label == "" {
-			return
-		}
+	// Check that the domain is not a public suffix, such as co.uk
+	if err := c.checkPublicSuffix(d); err != nil {
+		return "", err
 	}
 
 	// Check that the regular expression map has been initialized
@@ -151,25 +284,48 @@ func (c *Config) AddDomain(domain string) {
 		c.regexps = make(map[string]*regexp.Regexp)
 	}
 
-	// Create the regular expression for this domain
-	c.regexps[d] = dns.SubdomainRegex(d)
+	existed := slices.Contains(c.Scope.Domains, d)
+	// Create the regular expression for this domain, respecting the domain options
+	c.regexps[d] = c.Scope.domainRegex(d)
 	if c.regexps[d] != nil {
 		// Add the domain string to the list
 		c.Scope.Domains = append(c.Scope.Domains, d)
 	}
 
 	c.Scope.Domains = stringset.Deduplicate(c.Scope.Domains)
+	c.domainIndex.Store(nil)
+	if existed {
+		return "", nil
+	}
+	return d, nil
+}
+
+// ReindexScope clears the indexes of the root domains and blacklist, so they are rebuilt from the current
+// lists. It must be called after the Domains or Blacklist of the scope, or the scope itself, are changed
+// directly, since only the methods of the Config that change the lists keep the indexes up to date.
+func (c *Config) ReindexScope() {
+	c.Lock()
+	c.domainIndex.Store(nil)
+	c.Unlock()
+
+	c.blacklistLock.Lock()
+	c.blacklistIndex.Store(nil)
+	c.blacklistLock.Unlock()
 }
 
 // Domains returns the list of domain names currently in the configuration.
 func (c *Config) Domains() []string {
-	c.Lock()
-	defer c.Unlock()
+	c.RLock()
+	defer c.RUnlock()
 
+	if c.Scope == nil {
+		return nil
+	}
 	return c.Scope.Domains
 }
 
-// IsDomainInScope returns true if the DNS name in the parameter ends with a domain in the config list.
+// IsDomainInScope returns true if the DNS name in the parameter ends with a domain in the config list
+// and is permitted by the exact and max_depth options of that domain.
 func (c *Config) IsDomainInScope(name string) bool {
 	var discovered bool
 
@@ -181,15 +337,18 @@ func (c *Config) IsDomainInScope(name string) bool {
 }
 
 // WhichDomain returns the domain in the config list that the DNS name in the parameter ends with.
+// Domains with the exact or max_depth option only match the names that the option permits, and the
+// most specific domain is returned when the name ends with more than one domain in the list.
 func (c *Config) WhichDomain(name string) string {
-	n := strings.ToLower(strings.TrimSpace(name))
+	n := normalizeQuery(name)
 
-	for _, d := range c.Domains() {
-		if hasPathSuffix(n, d) {
-			return d
-		}
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.Scope == nil {
+		return ""
 	}
-	return ""
+	return currentTrie(&c.domainIndex, c.Scope.Domains).longestMatch(n, c.Scope.allowsName)
 }
 
 func hasPathSuffix(path, suffix string) bool {
@@ -213,48 +372,61 @@ func (c *Config) IsAddressInScope(addr string) bool {
 		return false
 	}
 
-	if len(c.Scope.Addresses) == 0 && len(c.Scope.CIDRs) == 0 {
-		return false
-	}
-	for _, cidr := range c.Scope.CIDRs {
-		if cidr.Contains(ip) {
-			return true
-		}
-	}
-	for _, a := range c.Scope.Addresses {
-		if a.Equal(ip) {
-			return true
-		}
-	}
-	return false
+	match, _ := c.whichNetwork(ip)
+	return match != ""
 }
 
 // BlacklistSubdomain adds a subdomain name to the config blacklist.
+// The name is normalized into the lowercase A-label form, and a name that is not valid is logged and skipped.
 func (c *Config) BlacklistSubdomain(name string) {
-	c.blacklistLock.Lock()
-	defer c.blacklistLock.Unlock()
+	if err := c.BlacklistSubdomainE(name); err != nil {
+		c.warnf("the subdomain name was not blacklisted: %v", err)
+	}
+}
 
-	set := stringset.New(c.Scope.Blacklist...)
-	defer set.Close()
-	set.Insert(strings.TrimSpace(name))
+// BlacklistSubdomainE adds a subdomain name to the config blacklist.
+// The name is normalized into the lowercase A-label form before it is added.
+func (c *Config) BlacklistSubdomainE(name string) error {
+	n, err := NormalizeDomain(name)
+	if err != nil {
+		return err
+	}
 
+	c.blacklistLock.Lock()
+	if c.Scope == nil {
+		c.blacklistLock.Unlock()
+		return errNoScope
+	}
+	set := stringset.New(c.Scope.Blacklist...)
+	added := !set.Has(n)
+	set.Insert(n)
 	c.Scope.Blacklist = set.Slice()
+	c.blacklistIndex.Store(nil)
+	set.Close()
+	c.blacklistLock.Unlock()
+
+	if added {
+		c.publishScopeEvent(BlacklistAdded, n)
+	}
+	return nil
 }
 
 // Blacklisted returns true is the name in the parameter ends with a subdomain name in the config blacklist.
 func (c *Config) Blacklisted(name string) bool {
-	c.blacklistLock.Lock()
-	defer c.blacklistLock.Unlock()
+	return c.whichBlacklisted(name) != ""
+}
 
-	n := strings.ToLower(strings.TrimSpace(name))
+// whichBlacklisted returns the entry in the config blacklist that the name in the parameter ends with.
+func (c *Config) whichBlacklisted(name string) string {
+	n := normalizeQuery(name)
 
-	for _, bl := range c.Scope.Blacklist {
-		if hasPathSuffix(n, bl) {
-			return true
-		}
-	}
+	c.blacklistLock.RLock()
+	defer c.blacklistLock.RUnlock()
 
-	return false
+	if c.Scope == nil {
+		return ""
+	}
+	return currentTrie(&c.blacklistIndex, c.Scope.Blacklist).shortestMatch(n)
 }
 
 // ParseIPs represents a slice of net.IP addresses.
@@ -0,0 +1,168 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// SeedOutsideScopeWarn causes seeds outside the scope to be accepted after logging a warning.
+	SeedOutsideScopeWarn = "warn"
+	// SeedOutsideScopeError causes seeds outside the scope to be rejected.
+	SeedOutsideScopeError = "error"
+)
+
+// loadSeedDerivationSettings reads the options that control how the seed and scope are derived from each other.
+func (c *Config) loadSeedDerivationSettings() error {
+	if raw, ok := c.Options["seed_from_scope"]; ok {
+		enabled, ok := raw.(bool)
+		if !ok {
+			return fmt.Errorf("failed to parse seed_from_scope setting, value is not a boolean")
+		}
+		c.SeedFromScope = enabled
+	}
+	if raw, ok := c.Options["scope_from_seed"]; ok {
+		enabled, ok := raw.(bool)
+		if !ok {
+			return fmt.Errorf("failed to parse scope_from_seed setting, value is not a boolean")
+		}
+		c.ScopeFromSeed = enabled
+	}
+
+	if raw, ok := c.Options["seed_outside_scope"]; ok {
+		policy, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("failed to parse seed_outside_scope setting, value is not a string")
+		}
+
+		switch p := strings.ToLower(strings.TrimSpace(policy)); p {
+		case SeedOutsideScopeWarn, SeedOutsideScopeError:
+			c.SeedOutsideScope = p
+		default:
+			return fmt.Errorf("seed_outside_scope must be %s or %s, got %s", SeedOutsideScopeWarn, SeedOutsideScopeError, policy)
+		}
+	}
+	return nil
+}
+
+// deriveSeedAndScope applies the derivation rules. When the seed lists no assets and seed_from_scope is
+// enabled, the lists left empty in the seed are copied from the scope. Otherwise, when the scope lists no
+// assets and scope_from_seed is enabled, the lists left empty in the scope are copied from the seed.
+func (c *Config) deriveSeedAndScope() {
+	if !c.Seed.hasAssets() && c.SeedFromScope {
+		c.Seed.fillFrom(c.Scope)
+	} else if !c.Scope.hasAssets() && c.ScopeFromSeed {
+		c.Scope.fillFrom(c.Seed)
+	}
+}
+
+// hasAssets returns true if the scope lists any domains, addresses, CIDRs, ASNs, organizations, email addresses or URLs.
+func (s *Scope) hasAssets() bool {
+	return len(s.Domains) > 0 || len(s.IP) > 0 || len(s.Addresses) > 0 || len(s.CIDRStrings) > 0 ||
+		len(s.CIDRs) > 0 || len(s.ASNs) > 0 || len(s.Organizations) > 0 || len(s.Emails) > 0 || len(s.URLs) > 0
+}
+
+// fillFrom copies the lists that are empty in this scope from the other scope. Ports are copied when this
+// scope has none other than the default ports.
+func (s *Scope) fillFrom(o *Scope) {
+	fill := func(dst *[]string, src []string) {
+		if len(*dst) == 0 {
+			*dst = slices.Clone(src)
+		}
+	}
+
+	if len(s.Domains) == 0 {
+		s.Domains = slices.Clone(o.Domains)
+		s.DomainOptions = make(map[string]*DomainOption, len(o.DomainOptions))
+		for name, opt := range o.DomainOptions {
+			copied := *opt
+			s.DomainOptions[name] = &copied
+		}
+	}
+	if len(s.IP) == 0 && len(s.Addresses) == 0 {
+		s.IP = slices.Clone(o.IP)
+		s.Addresses = slices.Clone(o.Addresses)
+	}
+	fill(&s.CIDRStrings, o.CIDRStrings)
+	if len(s.ASNs) == 0 {
+		s.ASNs = slices.Clone(o.ASNs)
+	}
+	fill(&s.Organizations, o.Organizations)
+	fill(&s.Emails, o.Emails)
+	fill(&s.URLs, o.URLs)
+	fill(&s.Blacklist, o.Blacklist)
+	fill(&s.Exclusions, o.Exclusions)
+	if len(s.Windows) == 0 {
+		s.Windows = slices.Clone(o.Windows)
+	}
+
+	hasPorts := len(o.PortStrings) > 0 || len(o.Ports) > 0
+	if hasPorts && len(s.PortStrings) == 0 && (len(s.Ports) == 0 || portCheck(s.Ports)) {
+		s.Ports = slices.Clone(o.Ports)
+		s.PortStrings = slices.Clone(o.PortStrings)
+	}
+}
+
+// checkSeedWithinScope applies the seed_outside_scope policy to the seeds that fall outside the scope.
+func (c *Config) checkSeedWithinScope() error {
+	outside := c.SeedsOutsideScope()
+	if len(outside) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("the seed includes entries outside the scope: %s", strings.Join(outside, ", "))
+	if c.SeedOutsideScope == SeedOutsideScopeError {
+		return errors.New(msg)
+	}
+
+	c.warnf("%s", msg)
+	return nil
+}
+
+// SeedsOutsideScope returns the seed domains, networks, ASNs, organizations, email addresses and URLs
+// that are not in scope. Seed networks partially in scope are reported by the ranges outside the scope.
+func (c *Config) SeedsOutsideScope() []string {
+	if c.Seed == nil || c.Scope == nil {
+		return nil
+	}
+
+	var outside []string
+	for _, d := range c.Seed.Domains {
+		if !c.IsDomainInScope(d) || c.Blacklisted(d) {
+			outside = append(outside, d)
+		}
+	}
+
+	c.RLock()
+	seed, scope := c.Seed.normalizedSets(), c.Scope.normalizedSets()
+	c.RUnlock()
+	for _, r := range subtractRanges(seed.networks, scope.networks) {
+		outside = append(outside, r.String())
+	}
+	for _, asn := range subtractInts(seed.asns, scope.asns) {
+		outside = append(outside, fmt.Sprintf("AS%d", asn))
+	}
+
+	for _, o := range c.Seed.Organizations {
+		if !c.IsOrganizationInScope(o) {
+			outside = append(outside, o)
+		}
+	}
+	for _, e := range c.Seed.Emails {
+		if !c.IsEmailInScope(e) {
+			outside = append(outside, e)
+		}
+	}
+	for _, u := range c.Seed.URLs {
+		if !c.IsURLInScope(u) {
+			outside = append(outside, u)
+		}
+	}
+	return outside
+}
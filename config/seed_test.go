@@ -0,0 +1,287 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSeedAndScopeDerivation(t *testing.T) {
+	tests := []struct {
+		name          string
+		seed          bool
+		scope         bool
+		seedFromScope bool
+		scopeFromSeed bool
+		wantSeed      []string
+		wantScope     []string
+	}{
+		{name: "both provided", seed: true, scope: true, seedFromScope: true, scopeFromSeed: true, wantSeed: []string{"www.owasp.org"}, wantScope: []string{"owasp.org"}},
+		{name: "both provided without seed_from_scope", seed: true, scope: true, scopeFromSeed: true, wantSeed: []string{"www.owasp.org"}, wantScope: []string{"owasp.org"}},
+		{name: "both provided without scope_from_seed", seed: true, scope: true, seedFromScope: true, wantSeed: []string{"www.owasp.org"}, wantScope: []string{"owasp.org"}},
+		{name: "both provided without derivation", seed: true, scope: true, wantSeed: []string{"www.owasp.org"}, wantScope: []string{"owasp.org"}},
+		{name: "seed only", seed: true, seedFromScope: true, scopeFromSeed: true, wantSeed: []string{"www.owasp.org"}, wantScope: []string{"www.owasp.org"}},
+		{name: "seed only without seed_from_scope", seed: true, scopeFromSeed: true, wantSeed: []string{"www.owasp.org"}, wantScope: []string{"www.owasp.org"}},
+		{name: "seed only without scope_from_seed", seed: true, seedFromScope: true, wantSeed: []string{"www.owasp.org"}},
+		{name: "seed only without derivation", seed: true, wantSeed: []string{"www.owasp.org"}},
+		{name: "scope only", scope: true, seedFromScope: true, scopeFromSeed: true, wantSeed: []string{"owasp.org"}, wantScope: []string{"owasp.org"}},
+		{name: "scope only without seed_from_scope", scope: true, scopeFromSeed: true, wantScope: []string{"owasp.org"}},
+		{name: "scope only without scope_from_seed", scope: true, seedFromScope: true, wantSeed: []string{"owasp.org"}, wantScope: []string{"owasp.org"}},
+		{name: "scope only without derivation", scope: true, wantScope: []string{"owasp.org"}},
+		{name: "neither provided", seedFromScope: true, scopeFromSeed: true},
+		{name: "neither provided without seed_from_scope", scopeFromSeed: true},
+		{name: "neither provided without scope_from_seed", seedFromScope: true},
+		{name: "neither provided without derivation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fmt.Sprintf("options:\n  seed_from_scope: %v\n  scope_from_seed: %v\n", tt.seedFromScope, tt.scopeFromSeed)
+			if tt.seed {
+				cfg += "seed:\n  domains:\n    - www.owasp.org\n"
+			}
+			if tt.scope {
+				cfg += "scope:\n  domains:\n    - owasp.org\n"
+			}
+
+			c := NewConfig()
+			if err := yaml.Unmarshal([]byte(cfg), c); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.loadSeedandScopeSettings(); err != nil {
+				t.Fatalf("loadSeedandScopeSettings() error = %v", err)
+			}
+			if !reflect.DeepEqual(c.Seed.Domains, tt.wantSeed) {
+				t.Errorf("seed domains = %v, want %v", c.Seed.Domains, tt.wantSeed)
+			}
+			if !reflect.DeepEqual(c.Scope.Domains, tt.wantScope) {
+				t.Errorf("scope domains = %v, want %v", c.Scope.Domains, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestSeedAndScopeDerivedLists(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       string
+		wantSeed  []int
+		wantScope []int
+		blacklist []string
+	}{
+		{
+			name: "scope uses the seed ports",
+			cfg: `
+seed:
+  domains:
+    - owasp.org
+  blacklist:
+    - internal.owasp.org
+  ports:
+    - 8080
+    - 8443`,
+			wantSeed:  []int{8080, 8443},
+			wantScope: []int{8080, 8443},
+			blacklist: []string{"internal.owasp.org"},
+		},
+		{
+			name: "scope keeps the default ports when the seed has none",
+			cfg: `
+seed:
+  domains:
+    - owasp.org`,
+			wantScope: DefaultPorts,
+		},
+		{
+			name: "scope keeps its own ports and blacklist",
+			cfg: `
+seed:
+  domains:
+    - owasp.org
+  blacklist:
+    - internal.owasp.org
+  ports:
+    - 8080
+scope:
+  blacklist:
+    - vpn.owasp.org
+  ports:
+    - 8443`,
+			wantSeed:  []int{8080},
+			wantScope: []int{8443},
+			blacklist: []string{"vpn.owasp.org"},
+		},
+		{
+			name: "seed uses the scope ports",
+			cfg: `
+scope:
+  domains:
+    - owasp.org
+  ports:
+    - 8443`,
+			wantSeed:  []int{8443},
+			wantScope: []int{8443},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			if err := yaml.Unmarshal([]byte(tt.cfg), c); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.loadSeedandScopeSettings(); err != nil {
+				t.Fatalf("loadSeedandScopeSettings() error = %v", err)
+			}
+			if len(c.Seed.Ports) > 0 || len(tt.wantSeed) > 0 {
+				if !reflect.DeepEqual(c.Seed.Ports, tt.wantSeed) {
+					t.Errorf("seed ports = %v, want %v", c.Seed.Ports, tt.wantSeed)
+				}
+			}
+			if !reflect.DeepEqual(c.Scope.Ports, tt.wantScope) {
+				t.Errorf("scope ports = %v, want %v", c.Scope.Ports, tt.wantScope)
+			}
+			if !reflect.DeepEqual(c.Scope.Blacklist, tt.blacklist) {
+				t.Errorf("scope blacklist = %v, want %v", c.Scope.Blacklist, tt.blacklist)
+			}
+		})
+	}
+}
+
+func TestSeedOutsideScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     string
+		outside []string
+		wantErr bool
+	}{
+		{
+			name: "seed within scope",
+			cfg: `
+seed:
+  domains:
+    - www.owasp.org
+  cidrs:
+    - 192.0.2.0/28
+  asns:
+    - 26808
+scope:
+  domains:
+    - owasp.org
+  cidrs:
+    - 192.0.2.0/24
+  asns:
+    - 26808`,
+		},
+		{
+			name: "entries outside the scope",
+			cfg: `
+seed:
+  domains:
+    - www.owasp.org
+    - example.com
+    - vpn.internal.owasp.org
+  ips:
+    - 192.0.2.250-192.0.3.5
+  asns:
+    - 13335
+  organizations:
+    - Example Inc
+  emails:
+    - security@owasp.org
+    - info@example.com
+  urls:
+    - https://www.owasp.org/
+    - https://example.com/
+scope:
+  domains:
+    - owasp.org
+  blacklist:
+    - internal.owasp.org
+  cidrs:
+    - 192.0.2.0/24`,
+			outside: []string{
+				"example.com", "vpn.internal.owasp.org", "192.0.3.0-192.0.3.5",
+				"AS13335", "example inc", "info@example.com", "https://example.com/",
+			},
+		},
+		{
+			name: "excluded seed address",
+			cfg: `
+seed:
+  ips:
+    - 192.0.2.10
+scope:
+  cidrs:
+    - 192.0.2.0/24
+  exclusions:
+    - 192.0.2.8/29`,
+			outside: []string{"192.0.2.10"},
+		},
+		{
+			name: "rejected by the error policy",
+			cfg: `
+seed:
+  domains:
+    - example.com
+scope:
+  domains:
+    - owasp.org
+options:
+  seed_outside_scope: error`,
+			outside: []string{"example.com"},
+			wantErr: true,
+		},
+		{
+			name: "invalid policy",
+			cfg: `
+options:
+  seed_outside_scope: ignore`,
+			wantErr: true,
+		},
+		{
+			name: "invalid derivation setting",
+			cfg: `
+options:
+  scope_from_seed: sometimes`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			if err := yaml.Unmarshal([]byte(tt.cfg), c); err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			c.Log = log.New(&buf, "", 0)
+			err := c.loadSeedandScopeSettings()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadSeedandScopeSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				for _, o := range tt.outside {
+					if !strings.Contains(err.Error(), o) {
+						t.Errorf("the error %q does not report %s", err.Error(), o)
+					}
+				}
+				return
+			}
+
+			if got := c.SeedsOutsideScope(); !reflect.DeepEqual(got, tt.outside) {
+				t.Errorf("SeedsOutsideScope() = %v, want %v", got, tt.outside)
+			}
+			if (buf.Len() > 0) != (len(tt.outside) > 0) {
+				t.Errorf("loadSeedandScopeSettings() logged %q for the seeds outside the scope %v", buf.String(), tt.outside)
+			}
+		})
+	}
+}
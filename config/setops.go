@@ -0,0 +1,875 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	neturl "net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The transport protocols tracked separately by the port set operations.
+var portProtocols = []string{"tcp", "udp"}
+
+// portSet is a bitmap of the port numbers in scope for each transport protocol.
+type portSet map[string][]uint64
+
+// Union returns a new Scope containing the domains, addresses, CIDRs, ASNs, ports, organizations, email
+// addresses and URLs found in either scope. A domain keeps the larger of the exact and max_depth limits set
+// for it in the scopes, and a subdomain is only dropped when its root permits all the names it does.
+// Blacklist entries are kept when the other scope does not include the name. The testing windows of both
+// scopes are kept, since the periods cannot be merged exactly. The results are normalized.
+func (s *Scope) Union(o *Scope) *Scope {
+	a, b := s.normalizedSets(), o.normalizedSets()
+
+	var blacklist []string
+	for _, bl := range a.blacklist {
+		if !b.includesName(bl) {
+			blacklist = append(blacklist, bl)
+		}
+	}
+	for _, bl := range b.blacklist {
+		if !a.includesName(bl) {
+			blacklist = append(blacklist, bl)
+		}
+	}
+
+	depths := make(map[string]int)
+	for _, sets := range []*scopeSets{a, b} {
+		for _, d := range sets.domains {
+			addDomainDepth(depths, d, sets.depth(d))
+		}
+	}
+	domains, depths := collapseDepthDomains(depths)
+
+	ports := a.ports.copy()
+	ports.apply(b.ports, func(x, y uint64) uint64 { return x | y })
+
+	return newScopeFromSets(&scopeSets{
+		domains:   domains,
+		depths:    depths,
+		blacklist: blacklist,
+		networks:  mergeRanges(append(a.networks, b.networks...)),
+		asns:      unionInts(a.asns, b.asns),
+		ports:     ports,
+		orgs:      sortedSet(append(append([]string{}, a.orgs...), b.orgs...)),
+		emails:    collapseEmails(append(append([]string{}, a.emails...), b.emails...)),
+		urls:      collapseURLs(append(append([]*neturl.URL{}, a.urls...), b.urls...)),
+		windows:   uniqueWindows(append(append([]*Window{}, a.windows...), b.windows...)),
+	})
+}
+
+// Intersect returns a new Scope containing the domains, addresses, CIDRs, ASNs, ports, organizations, email
+// addresses and URLs found in both scopes. The domains only permit the names allowed by the exact and max_depth
+// limits of both scopes. The blacklist entries of both scopes that fall within the resulting domains are kept.
+// The testing windows are intersected, so testing is only permitted when both scopes permit it. The results
+// are normalized.
+func (s *Scope) Intersect(o *Scope) *Scope {
+	a, b := s.normalizedSets(), o.normalizedSets()
+
+	depths := make(map[string]int)
+	for _, x := range a.domains {
+		for _, y := range b.domains {
+			if hasPathSuffix(y, x) {
+				if limit, ok := remainingDepth(x, a.depth(x), y); ok {
+					addDomainDepth(depths, y, minDepth(limit, b.depth(y)))
+				}
+			} else if hasPathSuffix(x, y) {
+				if limit, ok := remainingDepth(y, b.depth(y), x); ok {
+					addDomainDepth(depths, x, minDepth(limit, a.depth(x)))
+				}
+			}
+		}
+	}
+	domains, depths := collapseDepthDomains(depths)
+
+	var blacklist []string
+	for _, bl := range append(a.blacklist, b.blacklist...) {
+		if whichRoot(bl, domains) != "" {
+			blacklist = append(blacklist, bl)
+		}
+	}
+
+	var emails []string
+	for _, x := range a.emails {
+		for _, y := range b.emails {
+			if emailCovers(x, y) {
+				emails = append(emails, y)
+			} else if emailCovers(y, x) {
+				emails = append(emails, x)
+			}
+		}
+	}
+
+	var urls []*neturl.URL
+	for _, x := range a.urls {
+		for _, y := range b.urls {
+			if urlCovers(x, y) {
+				urls = append(urls, y)
+			} else if urlCovers(y, x) {
+				urls = append(urls, x)
+			}
+		}
+	}
+
+	ports := a.ports.copy()
+	ports.apply(b.ports, func(x, y uint64) uint64 { return x & y })
+
+	return newScopeFromSets(&scopeSets{
+		domains:   domains,
+		depths:    depths,
+		blacklist: blacklist,
+		networks:  intersectRanges(a.networks, b.networks),
+		asns:      intersectInts(a.asns, b.asns),
+		ports:     ports,
+		orgs:      intersectStrings(a.orgs, b.orgs),
+		emails:    collapseEmails(emails),
+		urls:      collapseURLs(urls),
+		windows:   intersectWindows(a.windows, b.windows),
+	})
+}
+
+// Subtract returns a new Scope containing the domains, addresses, CIDRs, ASNs, ports, organizations, email
+// addresses and URLs found in this scope, but not in the scope provided. Root domains of the other scope that
+// fall within the domains of this scope are added to the blacklist, and a domain is removed when it falls within
+// a domain of the other scope, even when the exact and max_depth limits of the other scope only remove some of
+// its names. An email domain or URL of this scope that includes entries of the other scope is kept, since the
+// scope cannot exclude them. The blacklist of the other scope is not added back, and the testing windows of this
+// scope are kept. The results are normalized.
+func (s *Scope) Subtract(o *Scope) *Scope {
+	a, b := s.normalizedSets(), o.normalizedSets()
+
+	var domains, blacklist []string
+	depths := make(map[string]int)
+	for _, d := range a.domains {
+		if whichRoot(d, b.domains) == "" {
+			domains = append(domains, d)
+			depths[d] = a.depth(d)
+		}
+	}
+	for _, bl := range append(a.blacklist, b.domains...) {
+		if whichRoot(bl, domains) != "" {
+			blacklist = append(blacklist, bl)
+		}
+	}
+
+	var emails []string
+	for _, e := range a.emails {
+		if !slices.ContainsFunc(b.emails, func(x string) bool { return emailCovers(x, e) }) {
+			emails = append(emails, e)
+		}
+	}
+
+	var urls []*neturl.URL
+	for _, u := range a.urls {
+		if !slices.ContainsFunc(b.urls, func(x *neturl.URL) bool { return urlCovers(x, u) }) {
+			urls = append(urls, u)
+		}
+	}
+
+	ports := a.ports.copy()
+	ports.apply(b.ports, func(x, y uint64) uint64 { return x &^ y })
+
+	return newScopeFromSets(&scopeSets{
+		domains:   domains,
+		depths:    depths,
+		blacklist: blacklist,
+		networks:  subtractRanges(a.networks, b.networks),
+		asns:      subtractInts(a.asns, b.asns),
+		ports:     ports,
+		orgs:      subtractStrings(a.orgs, b.orgs),
+		emails:    emails,
+		urls:      urls,
+		windows:   a.windows,
+	})
+}
+
+// Equal returns true if both scopes contain the same domains, with the same exact and max_depth limits, blacklist,
+// addresses, CIDRs, ASNs, ports, organizations, email addresses, URLs and testing windows after normalization.
+// For example, a scope with 192.0.2.0/25 and 192.0.2.128/25 equals one with 192.0.2.0/24.
+func (s *Scope) Equal(o *Scope) bool {
+	a, b := s.normalizedSets(), o.normalizedSets()
+
+	return reflect.DeepEqual(a.domains, b.domains) &&
+		reflect.DeepEqual(a.depths, b.depths) &&
+		reflect.DeepEqual(collapseDomains(a.blacklist), collapseDomains(b.blacklist)) &&
+		reflect.DeepEqual(a.networks, b.networks) &&
+		reflect.DeepEqual(a.asns, b.asns) &&
+		reflect.DeepEqual(a.ports.ranges(), b.ports.ranges()) &&
+		reflect.DeepEqual(a.orgs, b.orgs) &&
+		reflect.DeepEqual(a.emails, b.emails) &&
+		reflect.DeepEqual(urlStrings(a.urls), urlStrings(b.urls)) &&
+		reflect.DeepEqual(windowKeys(a.windows), windowKeys(b.windows))
+}
+
+// scopeSets holds the normalized contents of a Scope used by the set operations.
+type scopeSets struct {
+	domains []string
+	// The number of labels below each limited domain that are in scope, where zero is an exact domain
+	depths    map[string]int
+	blacklist []string
+	networks  []addrRange
+	asns      []int
+	ports     portSet
+	orgs      []string
+	emails    []string
+	urls      []*neturl.URL
+	windows   []*Window
+}
+
+// normalizedSets returns the normalized contents of the scope. The exclusions are removed from the networks.
+func (s *Scope) normalizedSets() *scopeSets {
+	sets := &scopeSets{ports: newPortSet(), depths: make(map[string]int)}
+	if s == nil {
+		return sets
+	}
+
+	depths := make(map[string]int)
+	for _, d := range s.Domains {
+		n := normalizeQuery(d)
+		depths[n] = -1
+		for name, opt := range s.DomainOptions {
+			if opt == nil || normalizeQuery(name) != n {
+				continue
+			}
+			if opt.Exact {
+				depths[n] = 0
+			} else if opt.MaxDepth > 0 {
+				depths[n] = opt.MaxDepth
+			}
+		}
+	}
+	sets.domains, sets.depths = collapseDepthDomains(depths)
+	for _, bl := range s.Blacklist {
+		sets.blacklist = append(sets.blacklist, normalizeQuery(bl))
+	}
+	sets.blacklist = sortedSet(sets.blacklist)
+
+	var ranges []addrRange
+	cidrs := s.CIDRs
+	if len(cidrs) == 0 {
+		cidrs = s.toCIDRs(s.CIDRStrings)
+	}
+	for _, ip := range s.IP {
+		if r, err := parseAddrRange(ip); err == nil {
+			ranges = append(ranges, r)
+		}
+	}
+	ranges = append(ranges, networkRanges(cidrs, s.Addresses)...)
+
+	excluded := s.ExcludedNets
+	if len(excluded) == 0 {
+		for _, e := range s.Exclusions {
+			if ipnet, err := parseExclusion(e); err == nil {
+				excluded = append(excluded, ipnet)
+			}
+		}
+	}
+	sets.networks = subtractRanges(mergeRanges(ranges), networkRanges(excluded, nil))
+
+	sets.asns = unionInts(s.ASNs, nil)
+
+	portRanges := s.PortRanges
+	if len(portRanges) == 0 {
+		for _, p := range s.Ports {
+			portRanges = append(portRanges, &PortRange{Start: p, End: p})
+		}
+	}
+	for _, r := range portRanges {
+		sets.ports.add(r)
+	}
+
+	for _, org := range s.Organizations {
+		if n := normalizeOrganization(org); n != "" {
+			sets.orgs = append(sets.orgs, n)
+		}
+	}
+	sets.orgs = sortedSet(sets.orgs)
+	for _, e := range s.Emails {
+		if email, err := NormalizeEmail(e); err == nil {
+			sets.emails = append(sets.emails, email)
+		}
+	}
+	sets.emails = collapseEmails(sets.emails)
+	for _, u := range s.URLs {
+		if prefix, err := parseURLPrefix(u); err == nil {
+			sets.urls = append(sets.urls, prefix)
+		}
+	}
+	sets.urls = collapseURLs(sets.urls)
+	sets.windows = uniqueWindows(s.Windows)
+	return sets
+}
+
+// depth returns the number of labels below the domain that are in scope, or -1 when it is not limited.
+func (sets *scopeSets) depth(domain string) int {
+	if d, found := sets.depths[domain]; found {
+		return d
+	}
+	return -1
+}
+
+// includesName returns true if the name falls within the domains, as permitted by their limits, and is not blacklisted.
+func (sets *scopeSets) includesName(name string) bool {
+	if whichRoot(name, sets.blacklist) != "" {
+		return false
+	}
+
+	for _, d := range sets.domains {
+		if hasPathSuffix(name, d) && domainCovers(d, sets.depth(d), name, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// newScopeFromSets builds a populated Scope from the normalized sets.
+func newScopeFromSets(sets *scopeSets) *Scope {
+	s := &Scope{
+		Domains:       sets.domains,
+		Blacklist:     collapseDomains(sets.blacklist),
+		ASNs:          sets.asns,
+		Organizations: sets.orgs,
+		Emails:        sets.emails,
+		Windows:       sets.windows,
+	}
+
+	for _, d := range sets.domains {
+		depth, found := sets.depths[d]
+		if !found || depth < 0 {
+			continue
+		}
+		if s.DomainOptions == nil {
+			s.DomainOptions = make(map[string]*DomainOption)
+		}
+		s.DomainOptions[d] = &DomainOption{Name: d, Exact: depth == 0, MaxDepth: depth}
+	}
+
+	for _, u := range sets.urls {
+		s.URLs = append(s.URLs, u.String())
+		s.URLPrefixes = append(s.URLPrefixes, u)
+	}
+
+	for _, r := range mergeRanges(sets.networks) {
+		for _, p := range r.prefixes() {
+			if p.IsSingleIP() {
+				s.IP = append(s.IP, p.Addr().String())
+				s.Addresses = append(s.Addresses, net.IP(p.Addr().AsSlice()))
+				continue
+			}
+			s.CIDRStrings = append(s.CIDRStrings, p.String())
+		}
+	}
+	s.CIDRs = s.toCIDRs(s.CIDRStrings)
+
+	s.PortRanges = sets.ports.ranges()
+	for _, r := range s.PortRanges {
+		s.PortStrings = append(s.PortStrings, r.String())
+	}
+	s.Ports = portNumbers(s.PortRanges)
+	return s
+}
+
+// domainCovers returns true if the domain, limited to depth labels below it, permits every name that the
+// subdomain does, where a negative depth is not limited.
+func domainCovers(domain string, depth int, sub string, subDepth int) bool {
+	if !hasPathSuffix(sub, domain) {
+		return false
+	}
+	if depth < 0 {
+		return true
+	}
+	return subDepth >= 0 && labelsBelow(sub, domain)+subDepth <= depth
+}
+
+// remainingDepth returns the depth that the domain, limited to depth labels below it, permits below the
+// subdomain, along with false when the domain does not permit the subdomain itself.
+func remainingDepth(domain string, depth int, sub string) (int, bool) {
+	if depth < 0 {
+		return -1, true
+	}
+	rest := depth - labelsBelow(sub, domain)
+	return rest, rest >= 0
+}
+
+// addDomainDepth records the depth for the domain, keeping the larger depth when the domain is already
+// recorded, where a negative depth is not limited.
+func addDomainDepth(depths map[string]int, domain string, depth int) {
+	if cur, found := depths[domain]; !found || (cur >= 0 && (depth < 0 || depth > cur)) {
+		depths[domain] = depth
+	}
+}
+
+// minDepth returns the smaller depth, where a negative depth is not limited.
+func minDepth(x, y int) int {
+	if x < 0 {
+		return y
+	}
+	if y < 0 {
+		return x
+	}
+	return min(x, y)
+}
+
+func labelsBelow(sub, domain string) int {
+	return strings.Count(sub, ".") - strings.Count(domain, ".")
+}
+
+// collapseDepthDomains returns the sorted domains, without those permitting only names that another domain
+// permits, along with the depths of the limited domains that remain.
+func collapseDepthDomains(depths map[string]int) ([]string, map[string]int) {
+	var domains []string
+	limits := make(map[string]int)
+
+	for _, d := range sortedSet(keysOfInts(depths)) {
+		if d == "" {
+			continue
+		}
+
+		var covered bool
+		for other, depth := range depths {
+			if other != d && other != "" && domainCovers(other, depth, d, depths[d]) {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+
+		domains = append(domains, d)
+		if depths[d] >= 0 {
+			limits[d] = depths[d]
+		}
+	}
+	return domains, limits
+}
+
+func keysOfInts(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// emailCovers returns true if the email entry includes the other entry, where an entry such as
+// @example.com includes every mailbox at the domain.
+func emailCovers(entry, other string) bool {
+	if entry == other {
+		return true
+	}
+	return strings.HasPrefix(entry, "@") && strings.HasSuffix(other, entry)
+}
+
+// collapseEmails returns the sorted email entries, without the mailboxes included by a domain entry.
+func collapseEmails(emails []string) []string {
+	var results []string
+
+	set := sortedSet(emails)
+	for _, e := range set {
+		if !slices.ContainsFunc(set, func(x string) bool { return x != e && emailCovers(x, e) }) {
+			results = append(results, e)
+		}
+	}
+	return results
+}
+
+// urlCovers returns true if the URLs use the same scheme, host and port, and the path of the other
+// URL is equal to or below the path of the first.
+func urlCovers(prefix, other *neturl.URL) bool {
+	return prefix.Scheme == other.Scheme && prefix.Host == other.Host && hasURLPathPrefix(other.Path, prefix.Path)
+}
+
+// collapseURLs returns the URLs sorted by their string form, without the duplicates and the URLs that
+// fall within another URL. Of the URLs that include each other, such as those without a path and with
+// the root path, the first in the order is kept.
+func collapseURLs(urls []*neturl.URL) []*neturl.URL {
+	var results []*neturl.URL
+
+	sorted := slices.Clone(urls)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].String() < sorted[j].String() })
+	for _, u := range sorted {
+		covered := slices.ContainsFunc(sorted, func(x *neturl.URL) bool {
+			if x.String() == u.String() || !urlCovers(x, u) {
+				return false
+			}
+			return !urlCovers(u, x) || x.String() < u.String()
+		})
+		if !covered && !slices.ContainsFunc(results, func(x *neturl.URL) bool { return x.String() == u.String() }) {
+			results = append(results, u)
+		}
+	}
+	return results
+}
+
+func urlStrings(urls []*neturl.URL) []string {
+	var results []string
+	for _, u := range urls {
+		results = append(results, u.String())
+	}
+	return results
+}
+
+// uniqueWindows returns the testing windows without the duplicates, sorted by their values.
+func uniqueWindows(windows []*Window) []*Window {
+	var results []*Window
+	seen := make(map[string]bool)
+
+	for _, w := range windows {
+		if w == nil {
+			continue
+		}
+		if key := w.key(); !seen[key] {
+			seen[key] = true
+			results = append(results, w)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].key() < results[j].key() })
+	return results
+}
+
+// closedWindowDate is the date of the window used when the intersection of the testing windows leaves no
+// period of time, so testing is never permitted for the targets of the window.
+const closedWindowDate = "0001-01-01"
+
+// intersectWindows returns the periods of time permitted by both lists of testing windows. A scope without
+// general windows, which do not list targets, permits testing at any time for the targets without windows.
+// The general windows are intersected with each other, and a window listing targets is intersected with the
+// general windows and the windows listing the same targets of the other scope. When the windows for some
+// targets do not overlap, a closed window is added, so the targets are not permitted at any time.
+func intersectWindows(a, b []*Window) []*Window {
+	always := &Window{}
+	withAlways := func(windows []*Window) []*Window {
+		for _, w := range windows {
+			if w != nil && len(w.Targets) == 0 {
+				return windows
+			}
+		}
+		return append(append([]*Window{}, windows...), always)
+	}
+
+	var results []*Window
+	restricted := make(map[string][]string)
+	found := make(map[string]bool)
+	for _, x := range withAlways(a) {
+		for _, y := range withAlways(b) {
+			if x == nil || y == nil {
+				continue
+			}
+
+			targets := x.Targets
+			if len(targets) == 0 {
+				targets = y.Targets
+			} else if len(y.Targets) > 0 && windowTargetsKey(x.Targets) != windowTargetsKey(y.Targets) {
+				continue
+			}
+			key := windowTargetsKey(targets)
+			if x != always || y != always {
+				restricted[key] = targets
+			}
+
+			if w := intersectWindow(x, y, targets); w != nil && w.key() != (&Window{}).key() {
+				results = append(results, w)
+				found[key] = true
+			}
+		}
+	}
+
+	for key, targets := range restricted {
+		if !found[key] {
+			w := &Window{Start: closedWindowDate, End: closedWindowDate, Targets: append([]string{}, targets...)}
+			_ = w.parse()
+			results = append(results, w)
+		}
+	}
+	return uniqueWindows(results)
+}
+
+// intersectWindow returns the window for the targets that permits testing only within both windows, or nil
+// when the windows do not overlap or cannot be intersected, such as windows in different time zones.
+func intersectWindow(x, y *Window, targets []string) *Window {
+	if x.Start == "" && x.End == "" && x.Hours == "" && x.TimeZone == "" {
+		return copyWindow(y, targets)
+	}
+	if y.Start == "" && y.End == "" && y.Hours == "" && y.TimeZone == "" {
+		return copyWindow(x, targets)
+	}
+
+	px, py := *x, *y
+	if px.parse() != nil || py.parse() != nil {
+		return nil
+	}
+	if !strings.EqualFold(strings.TrimSpace(x.TimeZone), strings.TrimSpace(y.TimeZone)) {
+		return nil
+	}
+
+	w := &Window{Start: x.Start, End: x.End, Hours: x.Hours, TimeZone: x.TimeZone, Targets: append([]string{}, targets...)}
+	if py.start.After(px.start) {
+		w.Start = y.Start
+	}
+	if px.end.IsZero() || (!py.end.IsZero() && py.end.Before(px.end)) {
+		w.End = y.End
+	}
+
+	switch {
+	case !px.hasHours:
+		w.Hours = y.Hours
+	case !py.hasHours || px.from == py.from && px.to == py.to:
+	case px.from < px.to && py.from < py.to:
+		from, to := max(px.from, py.from), min(px.to, py.to)
+		if from >= to {
+			return nil
+		}
+		w.Hours = formatWindowHour(from) + "-" + formatWindowHour(to)
+	default:
+		// The hours crossing midnight are not intersected
+		return nil
+	}
+
+	if w.parse() != nil {
+		return nil
+	}
+	return w
+}
+
+// copyWindow returns a parsed copy of the window for the targets.
+func copyWindow(w *Window, targets []string) *Window {
+	c := &Window{Start: w.Start, End: w.End, Hours: w.Hours, TimeZone: w.TimeZone, Targets: append([]string{}, targets...)}
+	if c.parse() != nil {
+		return nil
+	}
+	return c
+}
+
+// windowTargetsKey returns the targets lower-cased and sorted, for comparing the targets of windows.
+func windowTargetsKey(targets []string) string {
+	keys := make([]string, 0, len(targets))
+	for _, t := range targets {
+		keys = append(keys, strings.ToLower(strings.TrimSpace(t)))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// formatWindowHour returns the time of day in the layout of the window hours.
+func formatWindowHour(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+func windowKeys(windows []*Window) []string {
+	var keys []string
+	for _, w := range windows {
+		keys = append(keys, w.key())
+	}
+	return keys
+}
+
+// key returns the values of the window, with the targets sorted, for comparing windows.
+func (w *Window) key() string {
+	return strings.Join([]string{
+		strings.TrimSpace(w.Start),
+		strings.TrimSpace(w.End),
+		strings.TrimSpace(w.Hours),
+		strings.TrimSpace(w.TimeZone),
+		windowTargetsKey(w.Targets),
+	}, "|")
+}
+
+// collapseDomains removes duplicates and the names that are subdomains of another name in the list.
+func collapseDomains(names []string) []string {
+	var results []string
+
+	set := sortedSet(names)
+	for _, n := range set {
+		if n == "" {
+			continue
+		}
+
+		var covered bool
+		for _, other := range set {
+			if other != n && other != "" && hasPathSuffix(n, other) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			results = append(results, n)
+		}
+	}
+	return results
+}
+
+// whichRoot returns the name in the list that the name provided is equal to or a subdomain of.
+func whichRoot(name string, roots []string) string {
+	for _, r := range roots {
+		if hasPathSuffix(name, r) {
+			return r
+		}
+	}
+	return ""
+}
+
+func newPortSet() portSet {
+	ps := make(portSet)
+	for _, proto := range portProtocols {
+		ps[proto] = make([]uint64, (maxPortNumber+64)/64)
+	}
+	return ps
+}
+
+// add inserts the port range into the set. Ranges without a protocol are added for every protocol.
+func (ps portSet) add(r *PortRange) {
+	for _, proto := range portProtocols {
+		if r.Protocol != "" && r.Protocol != proto {
+			continue
+		}
+		for p := r.Start; p <= r.End; p++ {
+			ps[proto][p/64] |= 1 << (uint(p) % 64)
+		}
+	}
+}
+
+func (ps portSet) has(proto string, port int) bool {
+	return ps[proto][port/64]&(1<<(uint(port)%64)) != 0
+}
+
+func (ps portSet) copy() portSet {
+	c := newPortSet()
+	for proto, bits := range ps {
+		copy(c[proto], bits)
+	}
+	return c
+}
+
+// apply combines the bitmaps of the other set into this set using the operation provided.
+func (ps portSet) apply(o portSet, op func(x, y uint64) uint64) {
+	for proto, bits := range ps {
+		for i := range bits {
+			bits[i] = op(bits[i], o[proto][i])
+		}
+	}
+}
+
+// ranges returns the normalized port ranges in the set. Ports in scope for every protocol
+// are returned without a protocol.
+func (ps portSet) ranges() []*PortRange {
+	var cur *PortRange
+	var results []*PortRange
+
+	for port := minPortNumber; port <= maxPortNumber; port++ {
+		proto, found := ps.protocol(port)
+
+		if cur != nil && (!found || cur.Protocol != proto) {
+			results = append(results, cur)
+			cur = nil
+		}
+		if !found {
+			continue
+		}
+
+		if cur == nil {
+			cur = &PortRange{Protocol: proto, Start: port, End: port}
+		} else {
+			cur.End = port
+		}
+	}
+	if cur != nil {
+		results = append(results, cur)
+	}
+	return results
+}
+
+// protocol returns the protocol that the port is in scope for, or an empty string when the port
+// is in scope for every protocol. False is returned when the port is not in the set.
+func (ps portSet) protocol(port int) (string, bool) {
+	var found []string
+
+	for _, proto := range portProtocols {
+		if ps.has(proto, port) {
+			found = append(found, proto)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", false
+	case len(portProtocols):
+		return "", true
+	}
+	return found[0], true
+}
+
+func unionInts(a, b []int) []int {
+	set := make(map[int]struct{})
+	for _, v := range append(append([]int{}, a...), b...) {
+		set[v] = struct{}{}
+	}
+	return sortedInts(set)
+}
+
+func intersectInts(a, b []int) []int {
+	inb := make(map[int]struct{})
+	for _, v := range b {
+		inb[v] = struct{}{}
+	}
+
+	set := make(map[int]struct{})
+	for _, v := range a {
+		if _, found := inb[v]; found {
+			set[v] = struct{}{}
+		}
+	}
+	return sortedInts(set)
+}
+
+func subtractInts(a, b []int) []int {
+	inb := make(map[int]struct{})
+	for _, v := range b {
+		inb[v] = struct{}{}
+	}
+
+	set := make(map[int]struct{})
+	for _, v := range a {
+		if _, found := inb[v]; !found {
+			set[v] = struct{}{}
+		}
+	}
+	return sortedInts(set)
+}
+
+func intersectStrings(a, b []string) []string {
+	var results []string
+	for _, v := range a {
+		if slices.Contains(b, v) {
+			results = append(results, v)
+		}
+	}
+	return sortedSet(results)
+}
+
+func subtractStrings(a, b []string) []string {
+	var results []string
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			results = append(results, v)
+		}
+	}
+	return sortedSet(results)
+}
+
+func sortedInts(set map[int]struct{}) []int {
+	var results []int
+	for v := range set {
+		results = append(results, v)
+	}
+	sort.Ints(results)
+	return results
+}
@@ -0,0 +1,361 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/owasp-amass/open-asset-model/domain"
+)
+
+func setopsTestScope(t *testing.T, s *Scope) *Scope {
+	s.CIDRs = s.toCIDRs(s.CIDRStrings)
+	if err := s.populate(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestScopeUnion(t *testing.T) {
+	a := setopsTestScope(t, &Scope{
+		Domains:     []string{"owasp.org", "www.example.com"},
+		IP:          []string{"192.0.2.3-8"},
+		CIDRStrings: []string{"192.0.2.0/25"},
+		ASNs:        []int{26808},
+		PortStrings: []string{"80", "443/tcp"},
+		Blacklist:   []string{"dev.owasp.org", "legacy.owasp.org"},
+	})
+	b := setopsTestScope(t, &Scope{
+		Domains:     []string{"Example.com", "api.owasp.org"},
+		CIDRStrings: []string{"192.0.2.128/25", "2001:db8::/64"},
+		ASNs:        []int{15169, 26808},
+		PortStrings: []string{"53/udp", "443/udp"},
+		Blacklist:   []string{"test.example.com"},
+	})
+
+	got := a.Union(b)
+	if want := []string{"example.com", "owasp.org"}; !reflect.DeepEqual(got.Domains, want) {
+		t.Errorf("Union() domains = %v, want %v", got.Domains, want)
+	}
+	if want := []string{"dev.owasp.org", "legacy.owasp.org", "test.example.com"}; !reflect.DeepEqual(got.Blacklist, want) {
+		t.Errorf("Union() blacklist = %v, want %v", got.Blacklist, want)
+	}
+	if want := []string{"192.0.2.0/24", "2001:db8::/64"}; !reflect.DeepEqual(got.CIDRStrings, want) {
+		t.Errorf("Union() cidrs = %v, want %v", got.CIDRStrings, want)
+	}
+	if len(got.IP) != 0 {
+		t.Errorf("Union() addresses = %v, want none", got.IP)
+	}
+	if want := []int{15169, 26808}; !reflect.DeepEqual(got.ASNs, want) {
+		t.Errorf("Union() asns = %v, want %v", got.ASNs, want)
+	}
+	if want := []string{"53/udp", "80", "443"}; !reflect.DeepEqual(got.PortStrings, want) {
+		t.Errorf("Union() ports = %v, want %v", got.PortStrings, want)
+	}
+}
+
+func TestScopeIntersect(t *testing.T) {
+	a := setopsTestScope(t, &Scope{
+		Domains:     []string{"owasp.org", "example.com"},
+		IP:          []string{"198.51.100.10-198.51.100.20"},
+		CIDRStrings: []string{"192.0.2.0/24"},
+		ASNs:        []int{26808, 15169},
+		PortStrings: []string{"1-1024/tcp", "53/udp"},
+		Blacklist:   []string{"dev.owasp.org"},
+	})
+	b := setopsTestScope(t, &Scope{
+		Domains:     []string{"www.owasp.org", "example.net"},
+		IP:          []string{"198.51.100.16", "198.51.100.17"},
+		CIDRStrings: []string{"192.0.2.128/25", "203.0.113.0/24"},
+		ASNs:        []int{26808},
+		PortStrings: []string{"443", "53"},
+		Blacklist:   []string{"old.www.owasp.org", "test.example.net"},
+	})
+
+	got := a.Intersect(b)
+	if want := []string{"www.owasp.org"}; !reflect.DeepEqual(got.Domains, want) {
+		t.Errorf("Intersect() domains = %v, want %v", got.Domains, want)
+	}
+	if want := []string{"old.www.owasp.org"}; !reflect.DeepEqual(got.Blacklist, want) {
+		t.Errorf("Intersect() blacklist = %v, want %v", got.Blacklist, want)
+	}
+	if want := []string{"192.0.2.128/25", "198.51.100.16/31"}; !reflect.DeepEqual(got.CIDRStrings, want) {
+		t.Errorf("Intersect() cidrs = %v, want %v", got.CIDRStrings, want)
+	}
+	if want := []int{26808}; !reflect.DeepEqual(got.ASNs, want) {
+		t.Errorf("Intersect() asns = %v, want %v", got.ASNs, want)
+	}
+	if want := []string{"53", "443/tcp"}; !reflect.DeepEqual(got.PortStrings, want) {
+		t.Errorf("Intersect() ports = %v, want %v", got.PortStrings, want)
+	}
+}
+
+func TestScopeSubtract(t *testing.T) {
+	current := setopsTestScope(t, &Scope{
+		Domains:     []string{"owasp.org", "example.com", "example.net"},
+		IP:          []string{"198.51.100.7"},
+		CIDRStrings: []string{"192.0.2.0/24"},
+		ASNs:        []int{26808, 15169},
+		PortStrings: []string{"80", "443", "8000-8100"},
+	})
+	previous := setopsTestScope(t, &Scope{
+		Domains:     []string{"example.com", "www.owasp.org"},
+		IP:          []string{"198.51.100.7"},
+		CIDRStrings: []string{"192.0.2.0/25"},
+		ASNs:        []int{15169},
+		PortStrings: []string{"80", "443", "8050/tcp"},
+	})
+
+	got := current.Subtract(previous)
+	if want := []string{"example.net", "owasp.org"}; !reflect.DeepEqual(got.Domains, want) {
+		t.Errorf("Subtract() domains = %v, want %v", got.Domains, want)
+	}
+	if want := []string{"www.owasp.org"}; !reflect.DeepEqual(got.Blacklist, want) {
+		t.Errorf("Subtract() blacklist = %v, want %v", got.Blacklist, want)
+	}
+	if want := []string{"192.0.2.128/25"}; !reflect.DeepEqual(got.CIDRStrings, want) || len(got.IP) != 0 {
+		t.Errorf("Subtract() cidrs = %v and addresses = %v, want %v", got.CIDRStrings, got.IP, want)
+	}
+	if want := []int{26808}; !reflect.DeepEqual(got.ASNs, want) {
+		t.Errorf("Subtract() asns = %v, want %v", got.ASNs, want)
+	}
+	if want := []string{"8000-8049", "8050/udp", "8051-8100"}; !reflect.DeepEqual(got.PortStrings, want) {
+		t.Errorf("Subtract() ports = %v, want %v", got.PortStrings, want)
+	}
+}
+
+func TestScopeSubtractExclusions(t *testing.T) {
+	a := setopsTestScope(t, &Scope{
+		CIDRStrings: []string{"192.0.2.0/24"},
+		Exclusions:  []string{"192.0.2.0/25", "192.0.2.200"},
+	})
+
+	got := a.Subtract(&Scope{})
+	want := &Scope{IP: []string{"192.0.2.128-192.0.2.199", "192.0.2.201-192.0.2.255"}}
+	if !got.Equal(want) {
+		t.Errorf("Subtract() did not remove the exclusions: cidrs = %v, addresses = %v", got.CIDRStrings, got.IP)
+	}
+	if len(got.Exclusions) != 0 {
+		t.Errorf("Subtract() kept the exclusions: %v", got.Exclusions)
+	}
+}
+
+func TestScopeEqual(t *testing.T) {
+	a := setopsTestScope(t, &Scope{
+		Domains:     []string{"owasp.org", "www.owasp.org"},
+		CIDRStrings: []string{"192.0.2.0/25", "192.0.2.128/25"},
+		ASNs:        []int{26808, 15169},
+		PortStrings: []string{"80/tcp", "80/udp", "443"},
+	})
+	b := setopsTestScope(t, &Scope{
+		Domains:     []string{"OWASP.org."},
+		CIDRStrings: []string{"192.0.2.0/24"},
+		ASNs:        []int{15169, 26808},
+		PortStrings: []string{"443", "80"},
+	})
+	c := setopsTestScope(t, &Scope{
+		Domains:     []string{"owasp.org"},
+		CIDRStrings: []string{"192.0.2.0/24"},
+		ASNs:        []int{15169, 26808},
+		PortStrings: []string{"443", "80/tcp"},
+	})
+
+	if !a.Equal(b) {
+		t.Errorf("Equal() returned false for equivalent scopes")
+	}
+	if a.Equal(c) {
+		t.Errorf("Equal() returned true for scopes with different ports")
+	}
+	if !a.Union(b).Equal(a) || !a.Intersect(b).Equal(b) || !a.Subtract(b).Equal(&Scope{}) {
+		t.Errorf("the set operations on equivalent scopes did not return equivalent results")
+	}
+}
+
+func TestScopeSetOpsDomainOptions(t *testing.T) {
+	a := setopsTestScope(t, &Scope{
+		Domains:       []string{"owasp.org", "example.com"},
+		DomainOptions: map[string]*DomainOption{"owasp.org": {Name: "owasp.org", MaxDepth: 1}},
+	})
+	b := setopsTestScope(t, &Scope{
+		Domains: []string{"www.owasp.org", "example.com"},
+		DomainOptions: map[string]*DomainOption{
+			"example.com": {Name: "example.com", Exact: true},
+		},
+	})
+
+	union := a.Union(b)
+	if want := []string{"example.com", "owasp.org", "www.owasp.org"}; !reflect.DeepEqual(union.Domains, want) {
+		t.Errorf("Union() domains = %v, want %v", union.Domains, want)
+	}
+	if opt := union.DomainOptions["owasp.org"]; opt == nil || opt.MaxDepth != 1 {
+		t.Errorf("Union() dropped the max_depth of owasp.org: %+v", opt)
+	}
+	if _, found := union.DomainOptions["example.com"]; found {
+		t.Errorf("Union() limited example.com, which is not limited in the first scope")
+	}
+
+	inter := a.Intersect(b)
+	if want := []string{"example.com", "www.owasp.org"}; !reflect.DeepEqual(inter.Domains, want) {
+		t.Errorf("Intersect() domains = %v, want %v", inter.Domains, want)
+	}
+	if opt := inter.DomainOptions["www.owasp.org"]; opt == nil || !opt.Exact {
+		t.Errorf("Intersect() did not limit www.owasp.org to the depth left by owasp.org: %+v", opt)
+	}
+	if opt := inter.DomainOptions["example.com"]; opt == nil || !opt.Exact {
+		t.Errorf("Intersect() dropped the exact option of example.com: %+v", opt)
+	}
+
+	if diff := a.Subtract(&Scope{Domains: []string{"example.com"}}); diff.DomainOptions["owasp.org"] == nil {
+		t.Errorf("Subtract() dropped the max_depth of owasp.org")
+	}
+	if a.Equal(setopsTestScope(t, &Scope{Domains: []string{"owasp.org", "example.com"}})) {
+		t.Errorf("Equal() ignored the max_depth of owasp.org")
+	}
+}
+
+func TestScopeSetOpsAssets(t *testing.T) {
+	a := setopsTestScope(t, &Scope{
+		Organizations: []string{"OWASP Foundation", "Example Inc"},
+		Emails:        []string{"@owasp.org", "admin@example.com"},
+		URLs:          []string{"https://www.owasp.org/", "https://app.example.com/api"},
+		Windows:       []*Window{{Start: "2024-01-01", End: "2024-01-31"}},
+	})
+	b := setopsTestScope(t, &Scope{
+		Organizations: []string{"owasp  foundation"},
+		Emails:        []string{"jeff@owasp.org"},
+		URLs:          []string{"https://www.owasp.org/projects", "https://app.example.com/api/v1"},
+		Windows:       []*Window{{Hours: "09:00-17:00"}},
+	})
+
+	union := a.Union(b)
+	if want := []string{"example inc", "owasp foundation"}; !reflect.DeepEqual(union.Organizations, want) {
+		t.Errorf("Union() organizations = %v, want %v", union.Organizations, want)
+	}
+	if want := []string{"@owasp.org", "admin@example.com"}; !reflect.DeepEqual(union.Emails, want) {
+		t.Errorf("Union() emails = %v, want %v", union.Emails, want)
+	}
+	if want := []string{"https://app.example.com:443/api", "https://www.owasp.org:443/"}; !reflect.DeepEqual(union.URLs, want) {
+		t.Errorf("Union() urls = %v, want %v", union.URLs, want)
+	}
+	if len(union.Windows) != 2 {
+		t.Errorf("Union() windows = %d, want 2", len(union.Windows))
+	}
+
+	inter := a.Intersect(b)
+	if want := []string{"owasp foundation"}; !reflect.DeepEqual(inter.Organizations, want) {
+		t.Errorf("Intersect() organizations = %v, want %v", inter.Organizations, want)
+	}
+	if want := []string{"jeff@owasp.org"}; !reflect.DeepEqual(inter.Emails, want) {
+		t.Errorf("Intersect() emails = %v, want %v", inter.Emails, want)
+	}
+	if want := []string{"https://app.example.com:443/api/v1", "https://www.owasp.org:443/projects"}; !reflect.DeepEqual(inter.URLs, want) {
+		t.Errorf("Intersect() urls = %v, want %v", inter.URLs, want)
+	}
+
+	diff := a.Subtract(b)
+	if want := []string{"example inc"}; !reflect.DeepEqual(diff.Organizations, want) {
+		t.Errorf("Subtract() organizations = %v, want %v", diff.Organizations, want)
+	}
+	if want := []string{"@owasp.org", "admin@example.com"}; !reflect.DeepEqual(diff.Emails, want) {
+		t.Errorf("Subtract() emails = %v, want %v", diff.Emails, want)
+	}
+	if len(diff.Windows) != 1 || diff.Windows[0].Start != "2024-01-01" {
+		t.Errorf("Subtract() did not keep the windows of the scope: %v", diff.Windows)
+	}
+
+	if a.Equal(setopsTestScope(t, &Scope{
+		Organizations: []string{"OWASP Foundation", "Example Inc"},
+		Emails:        []string{"@owasp.org", "admin@example.com"},
+		URLs:          []string{"https://www.owasp.org/", "https://app.example.com/api"},
+	})) {
+		t.Errorf("Equal() ignored the testing windows")
+	}
+	if !a.Equal(a.Union(&Scope{})) {
+		t.Errorf("Equal() returned false for the union with an empty scope")
+	}
+}
+
+func TestScopeIntersectWindows(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	fqdn := &domain.FQDN{Name: "www.owasp.org"}
+
+	tests := []struct {
+		name    string
+		a       []*Window
+		b       []*Window
+		allowed map[string]bool
+	}{
+		{
+			name: "dates and hours",
+			a:    []*Window{{Start: "2024-01-01", End: "2024-01-31"}},
+			b:    []*Window{{Hours: "09:00-17:00"}},
+			allowed: map[string]bool{
+				"2024-01-10T10:00:00Z": true,
+				"2024-01-10T20:00:00Z": false,
+				"2024-02-10T10:00:00Z": false,
+			},
+		},
+		{
+			name: "overlapping hours",
+			a:    []*Window{{Hours: "09:00-17:00"}},
+			b:    []*Window{{Hours: "12:00-20:00"}},
+			allowed: map[string]bool{
+				"2024-01-10T10:00:00Z": false,
+				"2024-01-10T13:00:00Z": true,
+				"2024-01-10T18:00:00Z": false,
+			},
+		},
+		{
+			name: "disjoint dates",
+			a:    []*Window{{Start: "2024-01-01", End: "2024-01-31"}},
+			b:    []*Window{{Start: "2024-03-01", End: "2024-03-31"}},
+			allowed: map[string]bool{
+				"2024-01-10T10:00:00Z": false,
+				"2024-03-10T10:00:00Z": false,
+			},
+		},
+		{
+			name: "only one scope has windows",
+			b:    []*Window{{Hours: "09:00-17:00"}},
+			allowed: map[string]bool{
+				"2024-01-10T10:00:00Z": true,
+				"2024-01-10T20:00:00Z": false,
+			},
+		},
+		{
+			name: "target window outside the general window",
+			a:    []*Window{{Hours: "00:00-06:00", Targets: []string{"owasp.org"}}},
+			b:    []*Window{{Hours: "09:00-17:00"}},
+			allowed: map[string]bool{
+				"2024-01-10T03:00:00Z": false,
+				"2024-01-10T10:00:00Z": false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := setopsTestScope(t, &Scope{Domains: []string{"owasp.org"}, Windows: tt.a})
+			b := setopsTestScope(t, &Scope{Domains: []string{"owasp.org"}, Windows: tt.b})
+
+			for _, s := range []*Scope{a.Intersect(b), b.Intersect(a)} {
+				c := NewConfig()
+				c.Scope = s
+				for when, want := range tt.allowed {
+					if got := c.ActiveAllowedAt(fqdn, at(when)); got != want {
+						t.Errorf("ActiveAllowedAt(%s) = %v, want %v with the windows %v", when, got, want, windowKeys(s.Windows))
+					}
+				}
+			}
+		})
+	}
+}
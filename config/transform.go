@@ -5,7 +5,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -20,15 +22,22 @@ type Transformation struct {
 	Confidence int      `yaml:"confidence,omitempty" json:"confidence,omitempty"`
 	Exclude    []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
 	TTL        int      `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+	When       string   `yaml:"when,omitempty" json:"when,omitempty"`
+	// The compiled when clause
+	cond whenExpr
 }
 
 // Matches represents a collection of transform matches.
 type Matches struct {
 	lock sync.Mutex
-	to   map[string]struct {
-		ttl        int
-		confidence int
-	}
+	to   map[string]transformMatch
+}
+
+// transformMatch holds the values that apply to a single matched 'To' type.
+type transformMatch struct {
+	ttl        int
+	confidence int
+	priority   int
 }
 
 // loadTransformSettings processes the Transformations map from the configuration,
@@ -40,16 +49,28 @@ func (c *Config) loadTransformSettings(cfg *Config) error {
 	if err := c.loadGlobalTransformSettings(); err != nil {
 		return err
 	}
-	// Iterate through each transformation rule defined in the configuration.
-	for key, transformation := range c.Transformations {
-		// Initialize transformation if nil
-		if transformation == nil {
-			transformation = &Transformation{}      // default struct
-			c.Transformations[key] = transformation // assign it back to the map
-		}
+	// Expand the keys starting from ALL, lists and groups into a transformation per asset type.
+	resolved, err := c.expandTransformations()
+	if err != nil {
+		return err
+	}
+	// The rules are validated in the order of their keys, so the same errors are reported for every load.
+	keys := make([]string, 0, len(resolved))
+	for key := range resolved {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// The first pass validates each transformation rule on its own. The rules are resolved into copies,
+	// so the Transformations remain as written and a reload starts from the same values.
+	var errs []error
+	for _, key := range keys {
+		transformation := cloneTransformation(resolved[key])
+		resolved[key] = transformation
 		// Spit the key into 'From' and 'To' components.
 		if err := transformation.Split(key); err != nil {
-			return fmt.Errorf("error when splitting the key: %w", err)
+			errs = append(errs, fmt.Errorf("error when splitting the key: %w", err))
+			continue
 		}
 		// Apply the global confidence if no specific confidence is set for this transformation.
 		if transformation.Confidence == 0 {
@@ -58,16 +79,61 @@ func (c *Config) loadTransformSettings(cfg *Config) error {
 		if transformation.TTL == 0 {
 			transformation.TTL = c.DefaultTransformations.TTL
 		}
+		// Compile the when clause, so the conditions are validated with the configuration.
+		if err := transformation.compileWhen(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid when clause for the transformation %s: %w", key, err))
+			continue
+		}
 
-		err := transformation.Validate(c)
-		if err != nil {
-			return err
+		if err := transformation.Validate(c); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	// The second pass checks the complete set of rules for conflicts.
+	if err := checkNoneConflicts(resolved, keys); err != nil {
+		return err
+	}
+	c.resolveTransformPriorities(resolved)
+	c.resolvedTransforms.Store(&resolved)
+
+	// Publish the index of the loaded transformations for the lookups.
+	ix, err := c.buildTransformIndex(resolved)
+	if err != nil {
+		return err
+	}
+	c.transformIndex.Store(ix)
 	// If the loop completes with no conflicts, the function returns nil, indicating success.
 	return nil
 }
 
+// resolveTransformPriorities assigns a priority to the transformations that did not set one. A specific
+// rule takes the priority of the 'ALL' rule for the same 'From' type, and otherwise the global default.
+func (c *Config) resolveTransformPriorities(rules map[string]*Transformation) {
+	all := make(map[string]int)
+	for _, transformation := range rules {
+		if transformation.To == "all" {
+			if transformation.Priority == 0 {
+				transformation.Priority = c.DefaultTransformations.Priority
+			}
+			all[transformation.From] = transformation.Priority
+		}
+	}
+
+	for _, transformation := range rules {
+		if transformation.Priority != 0 {
+			continue
+		}
+		if p, found := all[transformation.From]; found {
+			transformation.Priority = p
+		} else {
+			transformation.Priority = c.DefaultTransformations.Priority
+		}
+	}
+}
+
 func (c *Config) loadGlobalTransformSettings() error {
 	// get the default_transfom_values in the config yaml
 	if dtv, ok := c.Options["default_transform_values"]; ok {
@@ -97,6 +163,15 @@ func (c *Config) loadGlobalTransformSettings() error {
 	return nil
 }
 
+// transformRules returns the loaded transformations, with the grouped keys expanded, or the Transformations
+// map when the transformations have not been loaded.
+func (c *Config) transformRules() map[string]*Transformation {
+	if rules := c.resolvedTransforms.Load(); rules != nil {
+		return *rules
+	}
+	return c.Transformations
+}
+
 // Split splits the key into 'From' and 'To' components, expecting a "->" delimiter.
 // Requires a non-nil Transformation pointer and a valid key format. Example: FQDN->IPaddress.
 func (t *Transformation) Split(key string) error {
@@ -116,114 +191,101 @@ func (t *Transformation) Split(key string) error {
 	return nil
 }
 
-// Validate checks the validity of a given transformation with respect to OAM &
-// previously registered transformations. The function ensures OAM compliance & that there are no conflicts
-// between transformations with 'none' (indicating no action) and other valid transformations
-// for the same 'From' type.
+// Validate checks the validity of a given transformation with respect to OAM. The 'From' type must be an
+// OAM asset type, and each of the excluded types must be an OAM asset type or the name of a configured
+// data source. The conflicts between the transformations are checked when the transformations are loaded.
 func (t *Transformation) Validate(c *Config) error {
-	if c.fromWithNone == nil {
-		c.fromWithNone = make(map[string]bool)
-	}
-	if c.fromWithValid == nil {
-		c.fromWithValid = make(map[string]bool)
+	if _, found := assetTypeName(t.From); !found {
+		return fmt.Errorf("invalid 'From' type: %s does not comply with OAM", t.From)
 	}
-	tfound := false
-	ffound := false
-	// Check if "From" and "To" is OAM compliant
-	for _, a := range oam.AssetList {
-		a := strings.ToLower(string(a))
-		if t.From == a {
-			ffound = true
-		}
-		if t.To == a || t.To == "none" || t.To == "all" {
-			tfound = true
+
+	var errs []error
+	for _, e := range t.Exclude {
+		if _, found := assetTypeName(e); found {
+			continue
 		}
-		// Used to prevent unnecessary iterations
-		if tfound && ffound {
-			break
+		if c != nil && c.GetDataSourceConfig(e) != nil {
+			continue
 		}
+		errs = append(errs, fmt.Errorf("invalid 'Exclude' type for the transformation %s->%s: %s is neither an OAM asset type nor a data source", t.From, t.To, e))
 	}
+	return errors.Join(errs...)
+}
 
-	if !ffound {
-		return fmt.Errorf("invalid 'From' type: %s does not comply with OAM", t.From)
-	}
-	// Check for a 'none' transformation, which indicates that no further processing is required for this 'From' type.
-	if t.To == "none" {
-		// Conflict arises if there's already a valid transformation for this 'From'.
-		if c.fromWithValid[t.From] {
-			return fmt.Errorf("invalid config: 'none' specified after a valid transformation for 'From' type: %s. 'None' should be the only transformation", t.From)
+// checkNoneConflicts checks that the 'none' transformations, which indicate that no further processing is
+// required for the 'From' type, are the only transformations for their type. Every conflicting rule is
+// reported, ordered by the keys provided.
+func checkNoneConflicts(rules map[string]*Transformation, keys []string) error {
+	var froms []string
+	none := make(map[string]string)
+	others := make(map[string][]string)
+	for _, key := range keys {
+		t := rules[key]
+
+		if _, found := others[t.From]; !found && none[t.From] == "" {
+			froms = append(froms, t.From)
 		}
-		c.fromWithNone[t.From] = true
-	} else { // For other valid transformations.
-		// Conflict arises if a 'none' transformation is already registered for this 'From'.
-		if c.fromWithNone[t.From] {
-			return fmt.Errorf("invalid config: valid transformation specified after 'none' for 'From' type: %s. 'None' should be the only transformation", t.From)
+		if t.To == "none" {
+			none[t.From] = key
+		} else {
+			others[t.From] = append(others[t.From], key)
 		}
-		// Mark this 'From' as having a valid transformation.
-		c.fromWithValid[t.From] = true
 	}
 
-	return nil
+	var errs []error
+	for _, from := range froms {
+		if n := none[from]; n != "" && len(others[from]) > 0 {
+			errs = append(errs, fmt.Errorf("invalid config: %s conflicts with %s for 'From' type: %s. 'None' should be the only transformation",
+				n, strings.Join(others[from], ", "), from))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // CheckTransformations checks if the given 'From' type has a valid transformation to any of the given 'To' types.
+// The 'ALL' rule for the 'From' type applies to the 'To' types without a specific rule, and a specific rule takes
+// the TTL, confidence or priority that it does not set from the 'ALL' rule. The when clauses are not evaluated,
+// so the conditional transformations are included in the matches.
 func (c *Config) CheckTransformations(from string, tos ...string) (*Matches, error) {
-	lower := strings.ToLower(from)
-	tomap := make(map[string]struct{})
-	results := &Matches{to: make(map[string]struct {
-		ttl        int
-		confidence int
-	})}
+	ix, err := c.TransformIndex()
+	if err != nil {
+		return nil, err
+	}
 
-	for _, v := range tos {
-		t := strings.ToLower(v)
-		tomap[t] = struct{}{}
+	return collectMatches(tos, func(to string) (TransformMatch, bool) {
+		return ix.Lookup(from, to)
+	})
+}
+
+// CheckAssetTransformations checks if the asset has a valid transformation to any of the given 'To' types,
+// in the same way as CheckTransformations, while only applying the transformations whose when clause holds
+// for the asset and its metadata. When the clause of a specific rule does not hold, its 'To' type is not
+// matched, even if an 'ALL' rule for the 'From' type would include it.
+func (c *Config) CheckAssetTransformations(asset oam.Asset, meta *AssetMetadata, tos ...string) (*Matches, error) {
+	if asset == nil {
+		return nil, fmt.Errorf("the asset is nil")
+	}
+
+	ix, err := c.TransformIndex()
+	if err != nil {
+		return nil, err
 	}
 
-	for _, transform := range c.Transformations {
-		if lower == transform.From {
-			if transform.To == "all" {
-				excludes := make(map[string]struct{})
-				for _, e := range transform.Exclude {
-					excludes[strings.ToLower(e)] = struct{}{}
-				}
-
-				for k := range tomap {
-					if _, ok := results.to[k]; !ok {
-						if _, found := excludes[k]; !found {
-							results.to[k] = struct {
-								ttl        int
-								confidence int
-							}{ttl: transform.checkTTL(k, c), confidence: transform.Confidence}
-						}
-					}
-				}
-				continue
-			} else if _, found := tomap[transform.To]; found {
-				// this only occurs if an ALL is specified
-				if old, ok := results.to[transform.To]; ok {
-					confidence := transform.Confidence
-					ttl := transform.checkTTL(transform.To, c)
-
-					if confidence == 0 { // if confidence is not set, use the ALL confidence
-						confidence = old.confidence
-					}
-
-					if ttl == 0 { // if ttl is not set, use the ALL ttl
-						ttl = old.ttl
-					}
-
-					results.to[transform.To] = struct {
-						ttl        int
-						confidence int
-					}{ttl: ttl, confidence: confidence}
-
-				} else {
-					results.to[transform.To] = struct {
-						ttl        int
-						confidence int
-					}{ttl: transform.checkTTL(transform.To, c), confidence: transform.Confidence}
-				}
+	return collectMatches(tos, func(to string) (TransformMatch, bool) {
+		return ix.LookupAsset(asset, meta, to)
+	})
+}
+
+// collectMatches looks up each of the 'To' types and returns the matches.
+func collectMatches(tos []string, lookup func(to string) (TransformMatch, bool)) (*Matches, error) {
+	results := &Matches{to: make(map[string]transformMatch)}
+
+	for _, v := range tos {
+		if m, found := lookup(v); found {
+			results.to[strings.ToLower(v)] = transformMatch{
+				ttl:        m.TTL,
+				confidence: m.Confidence,
+				priority:   m.Priority,
 			}
 		}
 	}
@@ -234,19 +296,19 @@ func (c *Config) CheckTransformations(from string, tos ...string) (*Matches, err
 	return results, nil
 }
 
-// checkTTl checks the TTL value for the given 'To' type in the given Config.
-// if the 'To' is a data source, it will return the TTL value from the data source config.
-// otherwise, it will return the default TTL value from the transformation config.
-func (t *Transformation) checkTTL(to string, c *Config) int {
-	if c != nil {
-		if c.DataSrcConfigs != nil {
-			ds := c.GetDataSourceConfig(to)
-			if ds != nil && ds.TTL > 0 {
-				return ds.TTL
-			}
-		}
+// compileWhen parses the when clause of the transformation.
+func (t *Transformation) compileWhen() error {
+	t.cond = nil
+	if strings.TrimSpace(t.When) == "" {
+		return nil
+	}
+
+	cond, err := parseWhen(t.When)
+	if err != nil {
+		return err
 	}
-	return t.TTL
+	t.cond = cond
+	return nil
 }
 
 // IsMatch checks if a valid transformation to the given 'To' type is present.
@@ -283,3 +345,37 @@ func (m *Matches) Confidence(to string) int {
 	}
 	return -1
 }
+
+// Priority returns the priority for a given 'To' type in the Matches struct.
+// If the 'To' type is not found, the function returns -1.
+func (m *Matches) Priority(to string) int {
+	if m.IsMatch(to) {
+		return m.to[strings.ToLower(to)].priority
+	}
+	return -1
+}
+
+// Targets returns the matched 'To' types ordered by priority, where a lower value is scheduled first
+// and 1 is the highest priority. Targets with the same priority are ordered by descending confidence,
+// and then by name, so the order is stable across calls.
+func (m *Matches) Targets() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	targets := make([]string, 0, len(m.to))
+	for to := range m.to {
+		targets = append(targets, to)
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		a, b := m.to[targets[i]], m.to[targets[j]]
+		if a.priority != b.priority {
+			return a.priority < b.priority
+		}
+		if a.confidence != b.confidence {
+			return a.confidence > b.confidence
+		}
+		return targets[i] < targets[j]
+	})
+	return targets
+}
@@ -6,6 +6,7 @@ package config
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -45,7 +46,7 @@ transformations:
   FQDN->none:
     priority: 2
   FQDN->ALL: 
-    exclude: [TLS,FQDN]
+    exclude: [TLSCertificate,FQDN]
   IPAddress->IPAddress:
     priority: 1
     confidence: 80
@@ -66,7 +67,7 @@ transformations:
     priority: 1
     confidence: 80
   FQDN->ALL: 
-    exclude: [TLS,FQDN]
+    exclude: [TLSCertificate,FQDN]
   IPAddress->IPAddress:
     priority: 1
     confidence: 80
@@ -96,7 +97,7 @@ transformations:
   FQDN->Amass:
     priority: 2
   FQDN->ALL: 
-    exclude: [RIRORG,FQDN]
+    exclude: [IPNetRecord,FQDN]
 `)
 
 var nonOAMfromYAML = []byte(`
@@ -110,7 +111,7 @@ transformations:
   Amass->DomainRecord:
     priority: 2
   FQDN->ALL: 
-    exclude: [RIRORG,FQDN]
+    exclude: [IPNetRecord,FQDN]
 `)
 
 // this data source info is here to test accuracy of considering data source ttl in the transformation
@@ -147,7 +148,7 @@ transformations:
   FQDN->DomainRecord:
     priority: 2
   FQDN->ALL: 
-    exclude: [TLS,FQDN]
+    exclude: [TLSCertificate,FQDN]
   IPAddress->FQDN:
     priority: 1
     confidence: 80
@@ -172,7 +173,7 @@ transformations:
     ttl: 1440
   FQDN->ALL:
     ttl: 1000
-    exclude: [TLS,FQDN]
+    exclude: [TLSCertificate,FQDN]
   IPAddress->FQDN:
     priority: 1
   IPAddress->DomainRecord:
@@ -224,7 +225,7 @@ func TestLoadTransformSettings(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
-		if conf.Transformations["FQDN->DomainRecord"].Confidence != 50 {
+		if conf.transformRules()["FQDN->DomainRecord"].Confidence != 50 {
 			t.Errorf("Expected confidence to be set to global value")
 		}
 	})
@@ -329,10 +330,7 @@ func TestSplit(t *testing.T) {
 
 func TestIsMatch(t *testing.T) {
 	m := &Matches{
-		to: map[string]struct {
-			ttl        int
-			confidence int
-		}{
+		to: map[string]transformMatch{
 			"ipaddress":    {},
 			"domainrecord": {},
 			"rirorg":       {},
@@ -428,12 +426,10 @@ func TestCheckTransformations(t *testing.T) {
 			tos:       []string{"ipaddress"},
 			expectErr: false,
 			expected: &Matches{
-				to: map[string]struct {
-					ttl        int
-					confidence int
-				}{
+				to: map[string]transformMatch{
 					"ipaddress": {
 						confidence: 80,
+						priority:   1,
 					},
 				},
 			},
@@ -444,20 +440,14 @@ func TestCheckTransformations(t *testing.T) {
 			tos:        []string{"rirorg"},
 			expectErr:  true,
 			errMessage: "zero transformation matches in the session config",
-			expected: &Matches{to: make(map[string]struct {
-				ttl        int
-				confidence int
-			})}},
+			expected:   &Matches{to: make(map[string]transformMatch)}},
 		{
 			name:      "Transformation to 'all'",
 			from:      "fqdn",
 			tos:       []string{"registrant", "rirorg"},
 			expectErr: false,
 			expected: &Matches{
-				to: map[string]struct {
-					ttl        int
-					confidence int
-				}{
+				to: map[string]transformMatch{
 					"registrant": {},
 				},
 			},
@@ -468,40 +458,28 @@ func TestCheckTransformations(t *testing.T) {
 			tos:        []string{"fqdn", "tls"},
 			expectErr:  true,
 			errMessage: "zero transformation matches in the session config",
-			expected: &Matches{to: make(map[string]struct {
-				ttl        int
-				confidence int
-			})}},
+			expected:   &Matches{to: make(map[string]transformMatch)}},
 		{
 			name:       "No \"from\" matches with config",
 			from:       "ip",
 			tos:        []string{"tls", "rirorg"},
 			expectErr:  true,
 			errMessage: "zero transformation matches in the session config",
-			expected: &Matches{to: make(map[string]struct {
-				ttl        int
-				confidence int
-			})}},
+			expected:   &Matches{to: make(map[string]transformMatch)}},
 		{
 			name:       "No \"to\" matches with config",
 			from:       "domainrecord",
 			tos:        []string{"fqdn"},
 			expectErr:  true,
 			errMessage: "zero transformation matches in the session config",
-			expected: &Matches{to: make(map[string]struct {
-				ttl        int
-				confidence int
-			})}},
+			expected:   &Matches{to: make(map[string]transformMatch)}},
 		{
 			name:       "Nil \"to\" matches with config",
 			from:       "fqdn",
 			tos:        []string{"rirorg"},
 			expectErr:  true,
 			errMessage: "zero transformation matches in the session config",
-			expected: &Matches{to: make(map[string]struct {
-				ttl        int
-				confidence int
-			})}},
+			expected:   &Matches{to: make(map[string]transformMatch)}},
 	}
 
 	var err error
@@ -535,6 +513,8 @@ func TestMatches(t *testing.T) {
 	_ = yaml.Unmarshal(validDataSrcYAML, conf.DataSrcConfigs)
 	_ = conf.loadTransformSettings(conf)
 	conf.DataSrcConfigs.ttlCheck()
+	// The TTLs of the data sources are resolved when the index is built
+	_ = conf.ReloadTransformations()
 
 	t.Run("Matching transformation", func(t *testing.T) {
 		m, err := conf.CheckTransformations("FQDN", "IPAddress", "Netblock", "AlienVault", "BinaryEdge")
@@ -615,3 +595,138 @@ func TestMatches(t *testing.T) {
 	})
 
 }
+
+func TestMatchesPriority(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+options:
+  default_transform_values:
+    priority: 4
+transformations:
+  FQDN->IPAddress:
+    priority: 1
+    confidence: 80
+  FQDN->DomainRecord:
+    priority: 2
+  FQDN->Netblock:
+    confidence: 90
+  FQDN->ALL:
+    priority: 3
+    exclude: [TLSCertificate]
+  IPAddress->Netblock:
+`), conf); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.loadTransformSettings(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		from       string
+		tos        []string
+		priorities map[string]int
+		targets    []string
+	}{
+		{
+			name:       "specific rules replace the ALL priority",
+			from:       "FQDN",
+			tos:        []string{"Netblock", "RIROrg", "DomainRecord", "IPAddress", "AutnumRecord", "TLSCertificate"},
+			priorities: map[string]int{"ipaddress": 1, "domainrecord": 2, "rirorg": 3, "autnumrecord": 3, "netblock": 3, "tlscertificate": -1},
+			targets:    []string{"ipaddress", "domainrecord", "netblock", "autnumrecord", "rirorg"},
+		},
+		{
+			name:       "default priority",
+			from:       "IPAddress",
+			tos:        []string{"Netblock"},
+			priorities: map[string]int{"netblock": 4},
+			targets:    []string{"netblock"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := conf.CheckTransformations(tt.from, tt.tos...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for to, want := range tt.priorities {
+				if got := m.Priority(to); got != want {
+					t.Errorf("Priority(%s) = %d, want %d", to, got, want)
+				}
+			}
+			if got := m.Targets(); !reflect.DeepEqual(got, tt.targets) {
+				t.Errorf("Targets() = %v, want %v", got, tt.targets)
+			}
+		})
+	}
+}
+
+func TestNoneConflicts(t *testing.T) {
+	cfg := []byte(`
+transformations:
+  FQDN->IPAddress:
+  FQDN->none:
+  FQDN->ALL:
+  IPAddress->none:
+  IPAddress->Netblock:
+  Netblock->none:
+  Netblock->IPAddress:
+    exclude: [Amass]
+  DomainRecord->none:
+`)
+	want := "invalid 'Exclude' type for the transformation netblock->ipaddress: Amass is neither an OAM asset type nor a data source"
+
+	var first string
+	for i := 0; i < 20; i++ {
+		_, err := prepareConfig(cfg)
+		if err == nil {
+			t.Fatal("Expected the errors, got nil")
+		}
+		if first == "" {
+			first = err.Error()
+		} else if err.Error() != first {
+			t.Fatalf("the error changed between loads:\n%s\n%s", first, err.Error())
+		}
+	}
+	if first != want {
+		t.Errorf("error = %q, want %q", first, want)
+	}
+
+	cfg = []byte(strings.Replace(string(cfg), "Amass", "BinaryEdge", 1))
+	conf := NewConfig()
+	if err := yaml.Unmarshal(validDataSrcYAML, conf.DataSrcConfigs); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(cfg, conf); err != nil {
+		t.Fatal(err)
+	}
+	err := conf.loadTransformSettings(conf)
+	if err == nil {
+		t.Fatal("Expected the conflicts, got nil")
+	}
+	want = "invalid config: FQDN->none conflicts with FQDN->ALL, FQDN->IPAddress for 'From' type: fqdn. 'None' should be the only transformation\n" +
+		"invalid config: IPAddress->none conflicts with IPAddress->Netblock for 'From' type: ipaddress. 'None' should be the only transformation\n" +
+		"invalid config: Netblock->none conflicts with Netblock->IPAddress for 'From' type: netblock. 'None' should be the only transformation"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestReloadTransformations(t *testing.T) {
+	conf, err := prepareConfig([]byte(`
+transformations:
+  FQDN->none:
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The 'none' rule from the previous load does not conflict with the new rules
+	conf.Transformations = map[string]*Transformation{"FQDN->IPAddress": {}}
+	if err := conf.loadTransformSettings(conf); err != nil {
+		t.Fatalf("loadTransformSettings() error = %v after replacing the rules", err)
+	}
+	if _, err := conf.CheckTransformations("FQDN", "IPAddress"); err != nil {
+		t.Errorf("CheckTransformations() error = %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// expandedTransformation is a concrete transformation produced from a key with several 'From' types.
+type expandedTransformation struct {
+	source string
+	key    string
+	t      *Transformation
+}
+
+// expandTransformations returns the transformations with the keys whose left side is ALL, a comma-separated
+// list or the name of a transform group, such as IPAddress,Netblock->RIROrg, replaced by a transformation
+// for each of the asset types. The Transformations map is not modified. A rule written for a single type
+// takes precedence over the expanded rules for the same types, and a 'none' rule written for a type removes
+// the type from the expansion. An error is returned when two expanded rules for the same types have
+// different settings.
+func (c *Config) expandTransformations() (map[string]*Transformation, error) {
+	groups, err := c.transformGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	explicit := make(map[string]struct{})
+	stopped := make(map[string]struct{})
+	for key := range c.Transformations {
+		from, to, found := strings.Cut(key, "->")
+		if !found || strings.Contains(to, "->") {
+			continue // the malformed key is reported by Split
+		}
+		if isGroupedFrom(from, groups) {
+			keys = append(keys, key)
+			continue
+		}
+		explicit[transformPair(from, to)] = struct{}{}
+		if strings.EqualFold(strings.TrimSpace(to), "none") {
+			stopped[strings.ToLower(strings.TrimSpace(from))] = struct{}{}
+		}
+	}
+	sort.Strings(keys)
+
+	var order []string
+	expanded := make(map[string]*expandedTransformation)
+	for _, key := range keys {
+		from, to, _ := strings.Cut(key, "->")
+
+		types, err := expandFromTypes(from, groups)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transformation %s: %w", key, err)
+		}
+		for _, t := range types {
+			if _, found := stopped[strings.ToLower(t)]; found {
+				continue
+			}
+
+			pair := transformPair(t, to)
+			if _, found := explicit[pair]; found {
+				continue
+			}
+
+			rule := cloneTransformation(c.Transformations[key])
+			if prev, found := expanded[pair]; found {
+				if !sameTransformSettings(prev.t, rule) {
+					return nil, fmt.Errorf("the transformations %s and %s both define %s with different settings", prev.source, key, prev.key)
+				}
+				continue
+			}
+
+			expanded[pair] = &expandedTransformation{
+				source: key,
+				key:    t + "->" + strings.TrimSpace(to),
+				t:      rule,
+			}
+			order = append(order, pair)
+		}
+	}
+
+	resolved := make(map[string]*Transformation, len(c.Transformations)+len(order))
+	for key, t := range c.Transformations {
+		if !slices.Contains(keys, key) {
+			resolved[key] = t
+		}
+	}
+	for _, pair := range order {
+		e := expanded[pair]
+		resolved[e.key] = e.t
+	}
+	return resolved, nil
+}
+
+// transformGroups returns the transform groups keyed by the lower-cased name, holding the OAM names of
+// the member types.
+func (c *Config) transformGroups() (map[string][]string, error) {
+	groups := make(map[string][]string, len(c.TransformGroups))
+
+	for name, members := range c.TransformGroups {
+		n := strings.ToLower(strings.TrimSpace(name))
+		if n == "" || n == "all" || n == "none" || strings.ContainsAny(n, ",>") {
+			return nil, fmt.Errorf("invalid transform group name: %q", name)
+		}
+		if _, found := assetTypeName(n); found {
+			return nil, fmt.Errorf("the transform group %s has the name of an OAM asset type", name)
+		}
+		if _, found := groups[n]; found {
+			return nil, fmt.Errorf("the transform group %s is defined more than once", name)
+		}
+		if len(members) == 0 {
+			return nil, fmt.Errorf("the transform group %s has no asset types", name)
+		}
+
+		var types []string
+		for _, m := range members {
+			t, found := assetTypeName(m)
+			if !found {
+				return nil, fmt.Errorf("the transform group %s contains %s, which does not comply with OAM", name, m)
+			}
+			types = append(types, t)
+		}
+		groups[n] = types
+	}
+	return groups, nil
+}
+
+// isGroupedFrom checks if the left side of a key holds more than a single asset type.
+func isGroupedFrom(from string, groups map[string][]string) bool {
+	f := strings.ToLower(strings.TrimSpace(from))
+	if f == "all" || strings.Contains(f, ",") {
+		return true
+	}
+	_, found := groups[f]
+	return found
+}
+
+// expandFromTypes returns the OAM names of the asset types on the left side of a key.
+func expandFromTypes(from string, groups map[string][]string) ([]string, error) {
+	var types []string
+
+	for _, item := range strings.Split(from, ",") {
+		i := strings.TrimSpace(item)
+
+		if strings.EqualFold(i, "all") {
+			for _, a := range oam.AssetList {
+				types = append(types, string(a))
+			}
+		} else if members, found := groups[strings.ToLower(i)]; found {
+			types = append(types, members...)
+		} else if t, found := assetTypeName(i); found {
+			types = append(types, t)
+		} else {
+			return nil, fmt.Errorf("'From' type %q is neither an OAM asset type nor a transform group", i)
+		}
+	}
+
+	var unique []string
+	for _, t := range types {
+		if !slices.Contains(unique, t) {
+			unique = append(unique, t)
+		}
+	}
+	return unique, nil
+}
+
+// transformPair returns the lower-cased 'From' and 'To' types as a key for comparisons.
+func transformPair(from, to string) string {
+	return strings.ToLower(strings.TrimSpace(from)) + "->" + strings.ToLower(strings.TrimSpace(to))
+}
+
+// cloneTransformation returns a copy of the transformation settings, without the 'From' and 'To' types.
+func cloneTransformation(t *Transformation) *Transformation {
+	if t == nil {
+		return &Transformation{}
+	}
+	return &Transformation{
+		Priority:   t.Priority,
+		Confidence: t.Confidence,
+		Exclude:    slices.Clone(t.Exclude),
+		TTL:        t.TTL,
+		When:       t.When,
+	}
+}
+
+// sameTransformSettings checks if the transformations have the same priority, confidence, TTL, exclusions
+// and when clause.
+func sameTransformSettings(a, b *Transformation) bool {
+	if a.Priority != b.Priority || a.Confidence != b.Confidence || a.TTL != b.TTL ||
+		strings.TrimSpace(a.When) != strings.TrimSpace(b.When) {
+		return false
+	}
+
+	ea, eb := make([]string, 0, len(a.Exclude)), make([]string, 0, len(b.Exclude))
+	for _, e := range a.Exclude {
+		ea = append(ea, strings.ToLower(strings.TrimSpace(e)))
+	}
+	for _, e := range b.Exclude {
+		eb = append(eb, strings.ToLower(strings.TrimSpace(e)))
+	}
+	sort.Strings(ea)
+	sort.Strings(eb)
+	return slices.Equal(slices.Compact(ea), slices.Compact(eb))
+}
@@ -0,0 +1,173 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExpandTransformations(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      string
+		keys     []string
+		priority map[string]int
+		errMsg   string
+	}{
+		{
+			name: "comma-separated list",
+			cfg: `
+transformations:
+  IPAddress, Netblock->AlienVault:
+    priority: 2
+  FQDN->IPAddress:`,
+			keys:     []string{"FQDN->IPAddress", "IPAddress->AlienVault", "Netblock->AlienVault"},
+			priority: map[string]int{"IPAddress->AlienVault": 2, "Netblock->AlienVault": 2},
+		},
+		{
+			name: "named group",
+			cfg: `
+transform_groups:
+  network: [IPAddress, Netblock, AutonomousSystem]
+transformations:
+  network->IPNetRecord:
+    priority: 3`,
+			keys:     []string{"AutonomousSystem->IPNetRecord", "IPAddress->IPNetRecord", "Netblock->IPNetRecord"},
+			priority: map[string]int{"Netblock->IPNetRecord": 3},
+		},
+		{
+			name: "a single type rule takes precedence",
+			cfg: `
+transform_groups:
+  Network: [ipaddress, netblock]
+transformations:
+  network,FQDN->AlienVault:
+    priority: 2
+  Netblock->AlienVault:
+    priority: 1`,
+			keys:     []string{"FQDN->AlienVault", "IPAddress->AlienVault", "Netblock->AlienVault"},
+			priority: map[string]int{"Netblock->AlienVault": 1, "IPAddress->AlienVault": 2},
+		},
+		{
+			name: "identical expanded rules",
+			cfg: `
+transform_groups:
+  network: [IPAddress, Netblock]
+transformations:
+  network->AlienVault:
+    exclude: [FQDN]
+  IPAddress,AutonomousSystem->AlienVault:
+    exclude: [fqdn]`,
+			keys: []string{"AutonomousSystem->AlienVault", "IPAddress->AlienVault", "Netblock->AlienVault"},
+		},
+		{
+			name: "conflicting expanded rules",
+			cfg: `
+transform_groups:
+  network: [IPAddress, Netblock]
+transformations:
+  network->AlienVault:
+    priority: 1
+  IPAddress,AutonomousSystem->AlienVault:
+    priority: 2`,
+			errMsg: "both define IPAddress->AlienVault",
+		},
+		{
+			name: "unknown type in a list",
+			cfg: `
+transformations:
+  IPAddress,Amass->AlienVault:`,
+			errMsg: `"Amass" is neither an OAM asset type`,
+		},
+		{
+			name: "group named after an asset type",
+			cfg: `
+transform_groups:
+  fqdn: [IPAddress]
+transformations: {}`,
+			errMsg: "has the name of an OAM asset type",
+		},
+		{
+			name: "group with an unknown type",
+			cfg: `
+transform_groups:
+  network: [IPAddress, Amass]
+transformations: {}`,
+			errMsg: "contains Amass",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			if err := yaml.Unmarshal([]byte(tt.cfg), c); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.loadTransformSettings(c)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("loadTransformSettings() error = %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadTransformSettings() error = %v", err)
+			}
+
+			var keys []string
+			for key := range c.transformRules() {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("keys = %v, want %v", keys, tt.keys)
+			}
+			for key, want := range tt.priority {
+				if got := c.transformRules()[key].Priority; got != want {
+					t.Errorf("%s priority = %d, want %d", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandTransformationsFromALL(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+transformations:
+  ALL->Source:
+    confidence: 90
+  Source->none:`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadTransformSettings(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// The transformations are left as written
+	if _, found := c.Transformations["ALL->Source"]; !found || len(c.Transformations) != 2 {
+		t.Errorf("the transformations were modified by the expansion: %v", c.Transformations)
+	}
+	// Source->none takes the place of Source->Source
+	if len(c.transformRules()) != len(oam.AssetList) {
+		t.Fatalf("got %d transformations, want %d", len(c.transformRules()), len(oam.AssetList))
+	}
+	if _, found := c.transformRules()["Source->Source"]; found {
+		t.Errorf("the expanded rule was added next to Source->none")
+	}
+	m, err := c.CheckTransformations("TLSCertificate", "Source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Confidence("Source") != 90 {
+		t.Errorf("Confidence() = %d, want 90", m.Confidence("Source"))
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// transformEdge is a transformation rule prepared for rendering as a diagram.
+type transformEdge struct {
+	from  string
+	to    string
+	kind  string // "all", "none", "asset" or "handler"
+	lines []string
+}
+
+// WriteTransformDOT writes the transformations to the writer as a Graphviz DOT digraph. Asset types
+// are ellipses and the targets that are not asset types, such as data sources, are boxes. 'ALL' rules
+// are dashed edges to the ALL node, and 'none' rules are bold edges to the none node.
+func (c *Config) WriteTransformDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	edges := c.transformEdges()
+
+	fmt.Fprintln(bw, "digraph transformations {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=ellipse];")
+	for _, n := range transformNodes(edges) {
+		switch n.kind {
+		case "all":
+			fmt.Fprintf(bw, "  %s [shape=doubleoctagon, style=filled, fillcolor=lightyellow];\n", dotQuote(n.name))
+		case "none":
+			fmt.Fprintf(bw, "  %s [shape=octagon, style=filled, fillcolor=lightgrey];\n", dotQuote(n.name))
+		case "handler":
+			fmt.Fprintf(bw, "  %s [shape=box];\n", dotQuote(n.name))
+		default:
+			fmt.Fprintf(bw, "  %s;\n", dotQuote(n.name))
+		}
+	}
+
+	for _, e := range edges {
+		var attrs []string
+		if len(e.lines) > 0 {
+			attrs = append(attrs, "label="+dotQuote(strings.Join(e.lines, "\n")))
+		}
+		switch e.kind {
+		case "all":
+			attrs = append(attrs, "style=dashed")
+		case "none":
+			attrs = append(attrs, "style=bold", "color=grey")
+		}
+
+		fmt.Fprintf(bw, "  %s -> %s", dotQuote(e.from), dotQuote(e.to))
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintln(bw, ";")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteTransformMermaid writes the transformations to the writer as a Mermaid flowchart. Asset types
+// are rectangles and the targets that are not asset types, such as data sources, are parallelograms.
+// 'ALL' rules are dotted links to the ALL hexagon, and 'none' rules are thick links to the none circle.
+func (c *Config) WriteTransformMermaid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	edges := c.transformEdges()
+
+	fmt.Fprintln(bw, "flowchart LR")
+	for _, n := range transformNodes(edges) {
+		id, label := mermaidID(n.name), mermaidQuote(n.name)
+
+		switch n.kind {
+		case "all":
+			fmt.Fprintf(bw, "  %s{{%s}}:::all\n", id, label)
+		case "none":
+			fmt.Fprintf(bw, "  %s((%s)):::none\n", id, label)
+		case "handler":
+			fmt.Fprintf(bw, "  %s[/%s/]\n", id, label)
+		default:
+			fmt.Fprintf(bw, "  %s[%s]\n", id, label)
+		}
+	}
+
+	for _, e := range edges {
+		link := "-->"
+		switch e.kind {
+		case "all":
+			link = "-.->"
+		case "none":
+			link = "==>"
+		}
+
+		fmt.Fprintf(bw, "  %s %s", mermaidID(e.from), link)
+		if len(e.lines) > 0 {
+			fmt.Fprintf(bw, "|%s|", mermaidQuote(strings.Join(e.lines, "<br/>")))
+		}
+		fmt.Fprintf(bw, " %s\n", mermaidID(e.to))
+	}
+	fmt.Fprintln(bw, "  classDef all fill:#ffffe0,stroke:#b8860b")
+	fmt.Fprintln(bw, "  classDef none fill:#d3d3d3,stroke:#696969")
+	return bw.Flush()
+}
+
+// transformEdges returns the labeled transformations sorted by the 'From' and 'To' types. Asset types
+// are shown with their OAM names, and the other targets as written in the configuration.
+func (c *Config) transformEdges() []*transformEdge {
+	var edges []*transformEdge
+
+	for key, t := range c.transformRules() {
+		from, to := transformEndpoints(key, t)
+		if from == "" {
+			continue
+		}
+		if t == nil {
+			t = &Transformation{}
+		}
+
+		e := &transformEdge{from: canonicalNames([]string{from})[0]}
+		switch to {
+		case "all":
+			e.kind, e.to = "all", "ALL"
+		case "none":
+			e.kind, e.to = "none", "none"
+		default:
+			if name, found := assetTypeName(to); found {
+				e.kind, e.to = "asset", name
+			} else {
+				e.kind, e.to = "handler", strings.TrimSpace(key[strings.LastIndex(key, "->")+2:])
+			}
+		}
+
+		if e.kind != "none" {
+			if t.Priority != 0 {
+				e.lines = append(e.lines, "priority: "+strconv.Itoa(t.Priority))
+			}
+			if t.Confidence != 0 {
+				e.lines = append(e.lines, "confidence: "+strconv.Itoa(t.Confidence))
+			}
+			if t.TTL != 0 {
+				e.lines = append(e.lines, "ttl: "+strconv.Itoa(t.TTL))
+			}
+		}
+		if w := strings.TrimSpace(t.When); w != "" {
+			e.lines = append(e.lines, "when: "+w)
+		}
+		if len(t.Exclude) > 0 {
+			var excludes []string
+			for _, x := range t.Exclude {
+				excludes = append(excludes, strings.ToLower(strings.TrimSpace(x)))
+			}
+			e.lines = append(e.lines, "exclude: "+strings.Join(canonicalTypes(excludes), ", "))
+		}
+		edges = append(edges, e)
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if a, b := strings.ToLower(edges[i].from), strings.ToLower(edges[j].from); a != b {
+			return a < b
+		}
+		return strings.ToLower(edges[i].to) < strings.ToLower(edges[j].to)
+	})
+	return edges
+}
+
+// transformNode is a node of the rendered diagram.
+type transformNode struct {
+	name string
+	kind string
+}
+
+// transformNodes returns the nodes of the edges in the order of their first appearance.
+func transformNodes(edges []*transformEdge) []transformNode {
+	var nodes []transformNode
+	seen := make(map[string]struct{})
+
+	add := func(name, kind string) {
+		if _, found := seen[name]; !found {
+			seen[name] = struct{}{}
+			nodes = append(nodes, transformNode{name: name, kind: kind})
+		}
+	}
+	for _, e := range edges {
+		add(e.from, "asset")
+		add(e.to, e.kind)
+	}
+	return nodes
+}
+
+// dotQuote returns the DOT quoted string for the text, keeping line breaks as DOT newlines.
+func dotQuote(text string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(text) + `"`
+}
+
+// mermaidID returns a Mermaid node identifier for the name, which only holds letters, digits and underscores.
+func mermaidID(name string) string {
+	var b strings.Builder
+
+	b.WriteString("n_")
+	for _, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "_%x_", r)
+		}
+	}
+	return b.String()
+}
+
+// mermaidQuote returns the Mermaid quoted string for the text.
+func mermaidQuote(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, "#quot;") + `"`
+}
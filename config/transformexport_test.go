@@ -0,0 +1,99 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var exportTransformYAML = []byte(`
+transformations:
+  FQDN->IPAddress:
+    priority: 1
+    confidence: 80
+  FQDN->ALL:
+    ttl: 720
+    exclude: [TLSCertificate, fqdn]
+  FQDN->Alien Vault:
+  IPAddress->none:
+`)
+
+func TestWriteTransformDOT(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal(exportTransformYAML, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadTransformSettings(c); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph transformations {
+  rankdir=LR;
+  node [shape=ellipse];
+  "FQDN";
+  "Alien Vault" [shape=box];
+  "ALL" [shape=doubleoctagon, style=filled, fillcolor=lightyellow];
+  "IPAddress";
+  "none" [shape=octagon, style=filled, fillcolor=lightgrey];
+  "FQDN" -> "Alien Vault" [label="priority: 5\nconfidence: 50\nttl: 1440"];
+  "FQDN" -> "ALL" [label="priority: 5\nconfidence: 50\nttl: 720\nexclude: FQDN, TLSCertificate", style=dashed];
+  "FQDN" -> "IPAddress" [label="priority: 1\nconfidence: 80\nttl: 1440"];
+  "IPAddress" -> "none" [style=bold, color=grey];
+}
+`
+	var buf bytes.Buffer
+	if err := c.WriteTransformDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("WriteTransformDOT() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteTransformMermaid(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal(exportTransformYAML, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadTransformSettings(c); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `flowchart LR
+  n_FQDN["FQDN"]
+  n_Alien_20_Vault[/"Alien Vault"/]
+  n_ALL{{"ALL"}}:::all
+  n_IPAddress["IPAddress"]
+  n_none(("none")):::none
+  n_FQDN -->|"priority: 5<br/>confidence: 50<br/>ttl: 1440"| n_Alien_20_Vault
+  n_FQDN -.->|"priority: 5<br/>confidence: 50<br/>ttl: 720<br/>exclude: FQDN, TLSCertificate"| n_ALL
+  n_FQDN -->|"priority: 1<br/>confidence: 80<br/>ttl: 1440"| n_IPAddress
+  n_IPAddress ==> n_none
+  classDef all fill:#ffffe0,stroke:#b8860b
+  classDef none fill:#d3d3d3,stroke:#696969
+`
+	var buf bytes.Buffer
+	if err := c.WriteTransformMermaid(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("WriteTransformMermaid() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestDiagramQuoting(t *testing.T) {
+	if got, want := dotQuote(`a "b" \c`+"\nd"), `"a \"b\" \\c\nd"`; got != want {
+		t.Errorf("dotQuote() = %s, want %s", got, want)
+	}
+	if got, want := mermaidQuote(`say "hi"`), `"say #quot;hi#quot;"`; got != want {
+		t.Errorf("mermaidQuote() = %s, want %s", got, want)
+	}
+	if got, want := mermaidID("Data-Source.1"), "n_Data_2d_Source_2e_1"; got != want {
+		t.Errorf("mermaidID() = %s, want %s", got, want)
+	}
+}
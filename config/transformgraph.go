@@ -0,0 +1,348 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// TransformAnalysis describes the directed graph formed by the transformations over the OAM asset types.
+type TransformAnalysis struct {
+	// The asset types that the analysis started from
+	Seeds []string `json:"seeds"`
+
+	// The asset types reachable from the seed types, including the seed types
+	Reachable []string `json:"reachable"`
+
+	// The transformations whose 'From' type cannot be reached from the seed types
+	Unreachable []string `json:"unreachable,omitempty"`
+
+	// The groups of asset types that transform back into each other
+	Cycles []*TransformCycle `json:"cycles,omitempty"`
+
+	// The asset types produced by a transformation that no transformation starts from
+	DeadEnds []string `json:"dead_ends,omitempty"`
+}
+
+// TransformCycle represents asset types that can be transformed back into themselves.
+type TransformCycle struct {
+	// The asset types that belong to the cycle
+	Types []string `json:"types"`
+
+	// The shortest path through the first of the types, such as FQDN->IPAddress->FQDN
+	Path []string `json:"path"`
+}
+
+// transformGraph holds the edges between the lower-cased asset types.
+type transformGraph struct {
+	edges map[string]map[string]struct{}
+	// The 'From' types with at least one transformation, including 'none'
+	from map[string]struct{}
+}
+
+// AnalyzeTransformations walks the transformations from the provided seed asset types, or from the types
+// of the assets in the seed when none are provided. An 'ALL' rule is an edge to every asset type that it
+// does not exclude, a 'none' rule ends the walk for its type, and the targets that are not asset types,
+// such as data sources, are handlers and not part of the graph.
+func (c *Config) AnalyzeTransformations(seeds ...string) (*TransformAnalysis, error) {
+	if len(seeds) == 0 {
+		seeds = c.Seed.assetTypes()
+	}
+
+	var start []string
+	for _, s := range seeds {
+		t, found := assetTypeName(s)
+		if !found {
+			return nil, fmt.Errorf("%s is not an OAM asset type", s)
+		}
+		start = append(start, strings.ToLower(t))
+	}
+
+	g := c.transformGraph()
+	reachable := g.reachable(start)
+
+	a := &TransformAnalysis{
+		Seeds:     canonicalTypes(start),
+		Reachable: canonicalTypes(keysOf(reachable)),
+		Cycles:    g.cycles(),
+		DeadEnds:  g.deadEnds(),
+	}
+	for key, t := range c.transformRules() {
+		if from, _ := transformEndpoints(key, t); from != "" {
+			if _, found := reachable[from]; !found {
+				a.Unreachable = append(a.Unreachable, key)
+			}
+		}
+	}
+	sort.Strings(a.Unreachable)
+	return a, nil
+}
+
+// transformGraph builds the graph of asset types from the transformations.
+func (c *Config) transformGraph() *transformGraph {
+	g := &transformGraph{
+		edges: make(map[string]map[string]struct{}),
+		from:  make(map[string]struct{}),
+	}
+
+	for key, t := range c.transformRules() {
+		from, to := transformEndpoints(key, t)
+		if from == "" {
+			continue
+		}
+		g.from[from] = struct{}{}
+
+		switch to {
+		case "none":
+		case "all":
+			excludes := make(map[string]struct{})
+			for _, e := range t.Exclude {
+				excludes[strings.ToLower(e)] = struct{}{}
+			}
+			for _, a := range oam.AssetList {
+				name := strings.ToLower(string(a))
+				if _, excluded := excludes[name]; !excluded {
+					g.addEdge(from, name)
+				}
+			}
+		default:
+			if _, found := assetTypeName(to); found {
+				g.addEdge(from, to)
+			}
+		}
+	}
+	return g
+}
+
+func (g *transformGraph) addEdge(from, to string) {
+	if g.edges[from] == nil {
+		g.edges[from] = make(map[string]struct{})
+	}
+	g.edges[from][to] = struct{}{}
+}
+
+// successors returns the sorted types that the given type transforms into.
+func (g *transformGraph) successors(from string) []string {
+	next := keysOf(g.edges[from])
+	sort.Strings(next)
+	return next
+}
+
+// reachable returns the types reachable from the start types, including the start types.
+func (g *transformGraph) reachable(start []string) map[string]struct{} {
+	seen := make(map[string]struct{})
+	queue := append([]string(nil), start...)
+	for _, s := range start {
+		seen[s] = struct{}{}
+	}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range g.successors(cur) {
+			if _, found := seen[next]; !found {
+				seen[next] = struct{}{}
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
+
+// deadEnds returns the types produced by a transformation that no transformation starts from.
+func (g *transformGraph) deadEnds() []string {
+	var ends []string
+	for _, tos := range g.edges {
+		for to := range tos {
+			if _, found := g.from[to]; !found {
+				ends = append(ends, to)
+			}
+		}
+	}
+	return canonicalTypes(ends)
+}
+
+// cycles returns the strongly connected components that contain a cycle, using Tarjan's algorithm.
+func (g *transformGraph) cycles() []*TransformCycle {
+	var nodes []string
+	for from := range g.edges {
+		nodes = append(nodes, from)
+	}
+	sort.Strings(nodes)
+
+	var index int
+	var stack []string
+	indices := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var results []*TransformCycle
+
+	var connect func(v string)
+	connect = func(v string) {
+		indices[v] = index
+		lowlink[v] = index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range g.successors(v) {
+			if _, visited := indices[w]; !visited {
+				connect(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], indices[w])
+			}
+		}
+		if lowlink[v] != indices[v] {
+			return
+		}
+
+		var component []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		// A single type is only a cycle when it transforms into itself
+		if _, self := g.edges[v][v]; len(component) > 1 || self {
+			results = append(results, g.cycle(component))
+		}
+	}
+
+	for _, v := range nodes {
+		if _, visited := indices[v]; !visited {
+			connect(v)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return strings.Join(results[i].Types, ",") < strings.Join(results[j].Types, ",")
+	})
+	return results
+}
+
+// cycle returns the cycle for the component, along with the shortest path through its first type.
+func (g *transformGraph) cycle(component []string) *TransformCycle {
+	members := make(map[string]struct{}, len(component))
+	for _, t := range component {
+		members[t] = struct{}{}
+	}
+	sort.Strings(component)
+	first := component[0]
+
+	// Breadth-first search within the component for the shortest path back to the first type
+	prev := make(map[string]string)
+	queue := []string{first}
+	for len(queue) > 0 && prev[first] == "" {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range g.successors(cur) {
+			if _, member := members[next]; !member {
+				continue
+			}
+			if _, found := prev[next]; !found {
+				prev[next] = cur
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	path := []string{first}
+	for cur := prev[first]; cur != first; cur = prev[cur] {
+		path = append(path, cur)
+	}
+	path = append(path, first)
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return &TransformCycle{
+		Types: canonicalTypes(component),
+		Path:  canonicalNames(path),
+	}
+}
+
+// assetTypes returns the asset types of the entries in the scope.
+func (s *Scope) assetTypes() []string {
+	if s == nil {
+		return nil
+	}
+
+	var types []string
+	add := func(present bool, t oam.AssetType) {
+		if present {
+			types = append(types, string(t))
+		}
+	}
+	add(len(s.Domains) > 0, oam.FQDN)
+	add(len(s.IP) > 0 || len(s.Addresses) > 0 || len(s.hostRanges) > 0, oam.IPAddress)
+	add(len(s.CIDRStrings) > 0 || len(s.CIDRs) > 0, oam.Netblock)
+	add(len(s.ASNs) > 0, oam.AutonomousSystem)
+	add(len(s.Organizations) > 0, oam.Organization)
+	add(len(s.Emails) > 0, oam.EmailAddress)
+	add(len(s.URLs) > 0, oam.URL)
+	return types
+}
+
+// transformEndpoints returns the lower-cased 'From' and 'To' types of the transformation, splitting the
+// key when the transformation has not been loaded. Empty values are returned for a malformed key.
+func transformEndpoints(key string, t *Transformation) (string, string) {
+	if t != nil && t.From != "" && t.To != "" {
+		return strings.ToLower(t.From), strings.ToLower(t.To)
+	}
+
+	var tmp Transformation
+	if err := tmp.Split(key); err != nil {
+		return "", ""
+	}
+	return tmp.From, tmp.To
+}
+
+// assetTypeName returns the OAM name of the asset type, compared without regard to case.
+func assetTypeName(name string) (string, bool) {
+	for _, a := range oam.AssetList {
+		if strings.EqualFold(string(a), strings.TrimSpace(name)) {
+			return string(a), true
+		}
+	}
+	return "", false
+}
+
+// canonicalTypes returns the sorted and unique OAM names of the lower-cased asset types.
+func canonicalTypes(types []string) []string {
+	names := canonicalNames(types)
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+// canonicalNames returns the OAM names of the lower-cased asset types, keeping their order.
+func canonicalNames(types []string) []string {
+	var names []string
+	for _, t := range types {
+		if name, found := assetTypeName(t); found {
+			names = append(names, name)
+		} else {
+			names = append(names, t)
+		}
+	}
+	return names
+}
+
+func keysOf(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
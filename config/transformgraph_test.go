@@ -0,0 +1,139 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAnalyzeTransformations(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         string
+		seeds       []string
+		reachable   []string
+		unreachable []string
+		cycles      []*TransformCycle
+		deadEnds    []string
+		wantErr     bool
+	}{
+		{
+			name: "cycle between two types",
+			cfg: `
+seed:
+  domains:
+    - owasp.org
+transformations:
+  FQDN->IPAddress:
+  IPAddress->FQDN:
+  IPAddress->Netblock:
+  Netblock->none:
+  Organization->Location:`,
+			reachable:   []string{"FQDN", "IPAddress", "Netblock"},
+			unreachable: []string{"Organization->Location"},
+			cycles:      []*TransformCycle{{Types: []string{"FQDN", "IPAddress"}, Path: []string{"FQDN", "IPAddress", "FQDN"}}},
+			deadEnds:    []string{"Location"},
+		},
+		{
+			name: "self transformation and data sources",
+			cfg: `
+transformations:
+  IPAddress->IPAddress:
+  IPAddress->AutonomousSystem:
+  AutonomousSystem->AutnumRecord:
+  AutonomousSystem->AlienVault:`,
+			seeds:     []string{"ipaddress"},
+			reachable: []string{"AutnumRecord", "AutonomousSystem", "IPAddress"},
+			cycles:    []*TransformCycle{{Types: []string{"IPAddress"}, Path: []string{"IPAddress", "IPAddress"}}},
+			deadEnds:  []string{"AutnumRecord"},
+		},
+		{
+			name: "longer cycle",
+			cfg: `
+transformations:
+  FQDN->DomainRecord:
+  DomainRecord->ContactRecord:
+  ContactRecord->FQDN:
+  ContactRecord->EmailAddress:
+  EmailAddress->none:`,
+			seeds:     []string{"FQDN"},
+			reachable: []string{"ContactRecord", "DomainRecord", "EmailAddress", "FQDN"},
+			cycles: []*TransformCycle{{
+				Types: []string{"ContactRecord", "DomainRecord", "FQDN"},
+				Path:  []string{"ContactRecord", "FQDN", "DomainRecord", "ContactRecord"},
+			}},
+		},
+		{
+			name: "ALL rule",
+			cfg: `
+transformations:
+  Netblock->ALL:
+    exclude: [Netblock, IPAddress, AutonomousSystem, FQDN, NetworkEndpoint, DomainRecord, AutnumRecord,
+      Location, Phone, EmailAddress, Person, Organization, SocketAddress, URL, Fingerprint, TLSCertificate,
+      ContactRecord, Source, Service]`,
+			seeds:     []string{"Netblock"},
+			reachable: []string{"IPNetRecord", "Netblock"},
+			deadEnds:  []string{"IPNetRecord"},
+		},
+		{
+			name: "seed types from the scope",
+			cfg: `
+scope:
+  cidrs:
+    - 192.0.2.0/24
+  asns:
+    - 26808
+transformations:
+  Netblock->IPAddress:
+  FQDN->IPAddress:`,
+			reachable:   []string{"AutonomousSystem", "IPAddress", "Netblock"},
+			unreachable: []string{"FQDN->IPAddress"},
+			deadEnds:    []string{"IPAddress"},
+		},
+		{
+			name:    "invalid seed type",
+			cfg:     `transformations: {}`,
+			seeds:   []string{"Amass"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			if err := yaml.Unmarshal([]byte(tt.cfg), c); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.loadSeedandScopeSettings(); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.loadTransformSettings(c); err != nil {
+				t.Fatal(err)
+			}
+
+			a, err := c.AnalyzeTransformations(tt.seeds...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AnalyzeTransformations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(a.Reachable, tt.reachable) {
+				t.Errorf("Reachable = %v, want %v", a.Reachable, tt.reachable)
+			}
+			if !reflect.DeepEqual(a.Unreachable, tt.unreachable) {
+				t.Errorf("Unreachable = %v, want %v", a.Unreachable, tt.unreachable)
+			}
+			if !reflect.DeepEqual(a.Cycles, tt.cycles) {
+				t.Errorf("Cycles = %v, want %v", a.Cycles, tt.cycles)
+			}
+			if !reflect.DeepEqual(a.DeadEnds, tt.deadEnds) {
+				t.Errorf("DeadEnds = %v, want %v", a.DeadEnds, tt.deadEnds)
+			}
+		})
+	}
+}
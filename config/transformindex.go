@@ -0,0 +1,312 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// TransformMatch holds the values that apply to a matched transformation.
+type TransformMatch struct {
+	TTL        int
+	Confidence int
+	Priority   int
+}
+
+// TransformIndex is a precompiled form of the transformations, keyed by the 'From' type, with the TTLs,
+// including those of the data sources, and the exclusions resolved when the index is built. Loading the
+// data sources rebuilds the index of the loaded transformations.
+//
+// The index is never modified after it has been built, so it can be used by any number of goroutines
+// without locking. Loading the transformations builds a new index and publishes it atomically, and the
+// lookups already holding the previous index keep using it.
+type TransformIndex struct {
+	c    *Config
+	from map[string]*indexedFrom
+	// The lower-cased form of the OAM names, the data source names and the names in the configuration
+	names map[string]string
+	// The TTLs of the data sources, for the targets of the 'ALL' rules
+	dsTTL map[string]int
+}
+
+// indexedFrom holds the transformations for a single 'From' type, keyed by the lower-cased names.
+type indexedFrom struct {
+	all      *indexedRule
+	excludes map[string]struct{}
+	to       map[string]*indexedRule
+}
+
+// indexedRule is a transformation with its values resolved for the 'To' type.
+type indexedRule struct {
+	match TransformMatch
+	cond  whenExpr
+}
+
+// TransformIndex returns the index built when the transformations were loaded. The index is built from
+// the Transformations map when the transformations have not been loaded. Changes made directly to the
+// Transformations, TransformGroups or data sources require ReloadTransformations to be called.
+func (c *Config) TransformIndex() (*TransformIndex, error) {
+	if ix := c.transformIndex.Load(); ix != nil {
+		return ix, nil
+	}
+
+	rules, err := c.expandTransformations()
+	if err != nil {
+		return nil, err
+	}
+	ix, err := c.buildTransformIndex(rules)
+	if err != nil {
+		return nil, err
+	}
+	c.transformIndex.Store(ix)
+	return ix, nil
+}
+
+// ReloadTransformations loads the Transformations and TransformGroups again, and publishes a new index for
+// the lookups. It must be called after the transformations are changed directly instead of being loaded
+// with the configuration. The previous index is kept when the transformations are not valid.
+func (c *Config) ReloadTransformations() error {
+	return c.loadTransformSettings(c)
+}
+
+// buildTransformIndex compiles the transformation rules into a new index. An 'ALL' rule applies to the targets
+// that have no specific rule, and a specific rule takes the TTL, confidence and priority that it does
+// not set from the 'ALL' rule, in the same way as CheckTransformations.
+func (c *Config) buildTransformIndex(rules map[string]*Transformation) (*TransformIndex, error) {
+	ix := &TransformIndex{
+		c:     c,
+		from:  make(map[string]*indexedFrom),
+		names: make(map[string]string),
+	}
+	for _, a := range oam.AssetList {
+		ix.addName(string(a))
+	}
+	dsTTL := c.dataSourceTTLs(ix)
+	ix.dsTTL = dsTTL
+
+	entry := func(from string) *indexedFrom {
+		f, found := ix.from[from]
+		if !found {
+			f = &indexedFrom{
+				excludes: make(map[string]struct{}),
+				to:       make(map[string]*indexedRule),
+			}
+			ix.from[from] = f
+		}
+		return f
+	}
+
+	var specific []string
+	for key, t := range rules {
+		if t == nil {
+			continue
+		}
+		from, to := transformEndpoints(key, t)
+		if from == "" {
+			continue
+		}
+		if to != "all" {
+			specific = append(specific, key)
+			continue
+		}
+
+		cond, err := t.compiledWhen()
+		if err != nil {
+			return nil, err
+		}
+
+		f := entry(from)
+		f.all = &indexedRule{
+			match: TransformMatch{TTL: t.TTL, Confidence: t.Confidence, Priority: t.Priority},
+			cond:  cond,
+		}
+		for _, e := range t.Exclude {
+			f.excludes[ix.addName(e)] = struct{}{}
+		}
+		ix.addName(key[:strings.Index(key, "->")])
+	}
+
+	for _, key := range specific {
+		t := rules[key]
+		from, to := transformEndpoints(key, t)
+
+		cond, err := t.compiledWhen()
+		if err != nil {
+			return nil, err
+		}
+
+		f := entry(from)
+		r := &indexedRule{
+			match: TransformMatch{TTL: t.TTL, Confidence: t.Confidence, Priority: t.Priority},
+			cond:  cond,
+		}
+		if ttl, found := dsTTL[to]; found {
+			r.match.TTL = ttl
+		}
+		if f.all != nil {
+			if _, excluded := f.excludes[to]; !excluded {
+				all := f.all.match
+				if ttl, found := dsTTL[to]; found {
+					all.TTL = ttl
+				}
+				if r.match.TTL == 0 {
+					r.match.TTL = all.TTL
+				}
+				if r.match.Confidence == 0 {
+					r.match.Confidence = all.Confidence
+				}
+				if r.match.Priority == 0 {
+					r.match.Priority = all.Priority
+				}
+			}
+		}
+
+		ix.addName(key[:strings.Index(key, "->")])
+		ix.addName(key[strings.LastIndex(key, "->")+2:])
+		f.to[to] = r
+	}
+	return ix, nil
+}
+
+// dataSourceTTLs returns the TTLs set for the data sources, keyed by the lower-cased names, and adds the
+// data source names to the index.
+func (c *Config) dataSourceTTLs(ix *TransformIndex) map[string]int {
+	c.RLock()
+	defer c.RUnlock()
+
+	ttls := make(map[string]int)
+	if c.DataSrcConfigs == nil {
+		return ttls
+	}
+
+	// The first data source with the name is used, as with GetDataSourceConfig
+	seen := make(map[string]struct{})
+	for _, ds := range c.DataSrcConfigs.Datasources {
+		if ds == nil {
+			continue
+		}
+
+		key := ix.addName(ds.Name)
+		if _, found := seen[key]; found {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if ds.TTL > 0 {
+			ttls[key] = ds.TTL
+		}
+	}
+	return ttls
+}
+
+// reindexTransformations rebuilds the index of the loaded transformations, so the lookups use the TTLs of
+// the current data sources. The index built from transformations that were not loaded is cleared instead.
+func (c *Config) reindexTransformations() error {
+	rules := c.resolvedTransforms.Load()
+	if rules == nil {
+		c.transformIndex.Store(nil)
+		return nil
+	}
+
+	ix, err := c.buildTransformIndex(*rules)
+	if err != nil {
+		return err
+	}
+	c.transformIndex.Store(ix)
+	return nil
+}
+
+// Lookup returns the values of the transformation from the 'From' type to the 'To' type, without
+// evaluating the when clauses. The names are compared without regard to case, and the lookup does not
+// allocate for names that are lower-cased, OAM names, data source names or written as in the configuration.
+func (ix *TransformIndex) Lookup(from, to string) (TransformMatch, bool) {
+	return ix.lookup(from, to, nil)
+}
+
+// LookupAsset returns the values of the transformation from the type of the asset to the 'To' type, when
+// the when clause of the transformation holds for the asset and its metadata. When the clause of a
+// specific rule does not hold, the 'To' type is not matched, even if an 'ALL' rule would include it.
+// The lookup only allocates to evaluate a when clause.
+func (ix *TransformIndex) LookupAsset(asset oam.Asset, meta *AssetMetadata, to string) (TransformMatch, bool) {
+	if asset == nil {
+		return TransformMatch{}, false
+	}
+	return ix.lookup(string(asset.AssetType()), to, func(cond whenExpr) bool {
+		return cond.eval(&whenEnv{c: ix.c, asset: asset, meta: meta})
+	})
+}
+
+// lookup finds the transformation, using holds to evaluate the when clauses, unless it is nil.
+func (ix *TransformIndex) lookup(from, to string, holds func(whenExpr) bool) (TransformMatch, bool) {
+	if ix == nil {
+		return TransformMatch{}, false
+	}
+
+	f, found := ix.from[ix.lower(from)]
+	if !found {
+		return TransformMatch{}, false
+	}
+
+	to = ix.lower(to)
+	if r, found := f.to[to]; found {
+		if r.cond != nil && holds != nil && !holds(r.cond) {
+			return TransformMatch{}, false
+		}
+		return r.match, true
+	}
+
+	if f.all == nil {
+		return TransformMatch{}, false
+	}
+	if _, excluded := f.excludes[to]; excluded {
+		return TransformMatch{}, false
+	}
+	if f.all.cond != nil && holds != nil && !holds(f.all.cond) {
+		return TransformMatch{}, false
+	}
+
+	m := f.all.match
+	if ttl, found := ix.dsTTL[to]; found {
+		m.TTL = ttl
+	}
+	return m, true
+}
+
+// addName records the lower-cased form of the name, and returns it.
+func (ix *TransformIndex) addName(name string) string {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+
+	if name != lower {
+		ix.names[name] = lower
+	}
+	return lower
+}
+
+// lower returns the lower-cased name, without allocating for the names known to the index.
+func (ix *TransformIndex) lower(name string) string {
+	if lower, found := ix.names[name]; found {
+		return lower
+	}
+	// ToLower returns the same string, without allocating, when the name is already lower-cased
+	return strings.ToLower(name)
+}
+
+// compiledWhen returns the compiled when clause of the transformation, parsing a clause that was not
+// compiled when the transformations were loaded.
+func (t *Transformation) compiledWhen() (whenExpr, error) {
+	if t.cond != nil || strings.TrimSpace(t.When) == "" {
+		return t.cond, nil
+	}
+
+	cond, err := parseWhen(t.When)
+	if err != nil {
+		return nil, fmt.Errorf("invalid when clause for the transformation %s->%s: %w", t.From, t.To, err)
+	}
+	return cond, nil
+}
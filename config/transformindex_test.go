@@ -0,0 +1,336 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/owasp-amass/open-asset-model/domain"
+	"gopkg.in/yaml.v3"
+)
+
+var indexTransformYAML = []byte(`
+transformations:
+  FQDN->IPAddress:
+    priority: 1
+    confidence: 80
+    ttl: 770
+  FQDN->DomainRecord:
+    when: in_scope
+  FQDN->TLSCertificate:
+    priority: 2
+  FQDN->ALL:
+    ttl: 1000
+    confidence: 60
+    exclude: [TLSCertificate, FQDN, BinaryEdge]
+  FQDN->BinaryEdge:
+  IPAddress->none:
+`)
+
+func loadIndexConfig(t testing.TB) *Config {
+	c := NewConfig()
+	if err := yaml.Unmarshal(indexTransformYAML, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(validDataSrcYAML, c.DataSrcConfigs); err != nil {
+		t.Fatal(err)
+	}
+	c.DataSrcConfigs.ttlCheck()
+	if err := yaml.Unmarshal([]byte("scope:\n  domains:\n    - owasp.org\n"), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadTransformSettings(c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestTransformIndexLookup(t *testing.T) {
+	c := loadIndexConfig(t)
+	ix, err := c.TransformIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from  string
+		to    string
+		found bool
+		want  TransformMatch
+	}{
+		{from: "FQDN", to: "IPAddress", found: true, want: TransformMatch{TTL: 770, Confidence: 80, Priority: 1}},
+		{from: "fqdn", to: "ipaddress", found: true, want: TransformMatch{TTL: 770, Confidence: 80, Priority: 1}},
+		{from: "Fqdn", to: "IPADDRESS", found: true, want: TransformMatch{TTL: 770, Confidence: 80, Priority: 1}},
+		// The ALL rule for the types without a specific rule, with the data source TTL
+		{from: "FQDN", to: "Netblock", found: true, want: TransformMatch{TTL: 1000, Confidence: 60, Priority: 5}},
+		{from: "FQDN", to: "AlienVault", found: true, want: TransformMatch{TTL: 4320, Confidence: 60, Priority: 5}},
+		// A specific rule takes the values that it does not set from the ALL rule
+		{from: "FQDN", to: "DomainRecord", found: true, want: TransformMatch{TTL: 1440, Confidence: 50, Priority: 5}},
+		// The ALL rule excludes the type, so the specific rule keeps its own values
+		{from: "FQDN", to: "TLSCertificate", found: true, want: TransformMatch{TTL: 1440, Confidence: 50, Priority: 2}},
+		{from: "FQDN", to: "BinaryEdge", found: true, want: TransformMatch{TTL: 1300, Confidence: 50, Priority: 5}},
+		{from: "FQDN", to: "FQDN"},
+		{from: "IPAddress", to: "Netblock"},
+		{from: "Netblock", to: "IPAddress"},
+	}
+	for _, tt := range tests {
+		got, found := ix.Lookup(tt.from, tt.to)
+		if found != tt.found || got != tt.want {
+			t.Errorf("Lookup(%s, %s) = %v, %v, want %v, %v", tt.from, tt.to, got, found, tt.want, tt.found)
+		}
+
+		m, err := c.CheckTransformations(tt.from, tt.to)
+		if (err == nil) != tt.found {
+			t.Errorf("CheckTransformations(%s, %s) error = %v, want a match %v", tt.from, tt.to, err, tt.found)
+			continue
+		}
+		if tt.found && (m.TTL(tt.to) != tt.want.TTL || m.Confidence(tt.to) != tt.want.Confidence || m.Priority(tt.to) != tt.want.Priority) {
+			t.Errorf("CheckTransformations(%s, %s) does not agree with Lookup", tt.from, tt.to)
+		}
+	}
+
+	if _, found := ix.LookupAsset(&domain.FQDN{Name: "www.owasp.org"}, nil, "DomainRecord"); !found {
+		t.Errorf("LookupAsset() did not match the name in scope")
+	}
+	if _, found := ix.LookupAsset(&domain.FQDN{Name: "www.example.com"}, nil, "DomainRecord"); found {
+		t.Errorf("LookupAsset() matched the name out of scope")
+	}
+}
+
+func TestTransformIndexReload(t *testing.T) {
+	c := loadIndexConfig(t)
+	ix, err := c.TransformIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := c.TransformIndex(); again != ix {
+		t.Fatalf("TransformIndex() rebuilt the index without a reload")
+	}
+
+	// Replacing a rule keeps the size of the map, and is applied by the reload
+	delete(c.Transformations, "FQDN->TLSCertificate")
+	c.Transformations["Netblock->IPAddress"] = &Transformation{Priority: 3}
+	if err := c.ReloadTransformations(); err != nil {
+		t.Fatal(err)
+	}
+	ix2, err := c.TransformIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, found := ix2.Lookup("Netblock", "IPAddress"); !found || m.Priority != 3 {
+		t.Errorf("the reloaded index returned %v, %v for the new rule", m, found)
+	}
+	if _, found := ix2.Lookup("FQDN", "TLSCertificate"); found {
+		t.Errorf("the reloaded index matched the deleted rule")
+	}
+	// The previous index is not modified
+	if _, found := ix.Lookup("Netblock", "IPAddress"); found {
+		t.Errorf("the previous index was modified")
+	}
+
+	// The previous index is kept when the transformations are not valid
+	c.Transformations = map[string]*Transformation{"Amass->IPAddress": nil}
+	if err := c.ReloadTransformations(); err == nil {
+		t.Errorf("ReloadTransformations() did not return an error for the invalid transformation")
+	}
+	if ix3, _ := c.TransformIndex(); ix3 != ix2 {
+		t.Errorf("the index was replaced by the invalid transformations")
+	}
+}
+
+func TestTransformIndexReloadAllPriority(t *testing.T) {
+	c, err := prepareConfig([]byte(`
+transformations:
+  FQDN->ALL:
+    priority: 3
+  FQDN->Netblock:
+    confidence: 80
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix, err := c.TransformIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, found := ix.Lookup("FQDN", "Netblock"); !found || m.Priority != 3 {
+		t.Fatalf("Lookup() = %v, %v, want the priority of the 'ALL' rule", m, found)
+	}
+
+	// The resolved values are not written back, so the specific rule inherits the new priority
+	if got := c.Transformations["FQDN->Netblock"].Priority; got != 0 {
+		t.Errorf("the load set the priority of the transformation to %d", got)
+	}
+	c.Transformations["FQDN->ALL"].Priority = 1
+	if err := c.ReloadTransformations(); err != nil {
+		t.Fatal(err)
+	}
+	if ix, err = c.TransformIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if m, found := ix.Lookup("FQDN", "Netblock"); !found || m.Priority != 1 {
+		t.Errorf("Lookup() = %v, %v after the reload, want priority 1", m, found)
+	}
+}
+
+func TestTransformIndexDataSourceTTL(t *testing.T) {
+	c := loadIndexConfig(t)
+	ix, err := c.TransformIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The data source TTLs are resolved when the index is built, and applied by the reload
+	c.GetDataSourceConfig("BinaryEdge").TTL = 42
+	c.DataSrcConfigs.Datasources = append(c.DataSrcConfigs.Datasources, &DataSource{Name: "Shodan", TTL: 7})
+	if err := c.ReloadTransformations(); err != nil {
+		t.Fatal(err)
+	}
+	ix2, err := c.TransformIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, found := ix2.Lookup("FQDN", "BinaryEdge"); !found || m.TTL != 42 {
+		t.Errorf("Lookup() = %v, %v, want the TTL of the data source changed before the reload", m, found)
+	}
+	if m, found := ix2.Lookup("FQDN", "Shodan"); !found || m.TTL != 7 {
+		t.Errorf("Lookup() = %v, %v, want the TTL of the data source added before the reload", m, found)
+	}
+	// The previous index keeps the TTLs it was built with
+	if m, found := ix.Lookup("FQDN", "BinaryEdge"); !found || m.TTL == 42 {
+		t.Errorf("Lookup() = %v, %v, the previous index was modified", m, found)
+	}
+}
+
+func TestTransformIndexDataSourceReload(t *testing.T) {
+	c := loadIndexConfig(t)
+
+	path := filepath.Join(t.TempDir(), "datasources.yaml")
+	if err := os.WriteFile(path, []byte("datasources:\n  - name: BinaryEdge\n    ttl: 2880\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c.Options["datasources"] = path
+	// Loading the data sources rebuilds the index of the loaded transformations
+	if err := c.loadDataSourceSettings(c); err != nil {
+		t.Fatal(err)
+	}
+	ix, err := c.TransformIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, found := ix.Lookup("FQDN", "BinaryEdge"); !found || m.TTL != 2880 {
+		t.Errorf("Lookup() = %v, %v, want the TTL of the reloaded data source", m, found)
+	}
+}
+
+func TestTransformIndexAllocations(t *testing.T) {
+	c := loadIndexConfig(t)
+	ix, err := c.TransformIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookups := [][2]string{{"FQDN", "IPAddress"}, {"fqdn", "netblock"}, {"FQDN", "AlienVault"}, {"FQDN", "TLSCertificate"}, {"Netblock", "FQDN"}}
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, l := range lookups {
+			_, _ = ix.Lookup(l[0], l[1])
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Lookup() allocated %v times per run", allocs)
+	}
+
+	asset := &domain.FQDN{Name: "www.owasp.org"}
+	allocs = testing.AllocsPerRun(100, func() {
+		_, _ = ix.LookupAsset(asset, nil, "IPAddress")
+	})
+	if allocs != 0 {
+		t.Errorf("LookupAsset() allocated %v times per run for an unconditional rule", allocs)
+	}
+}
+
+func TestTransformIndexConcurrency(t *testing.T) {
+	c := loadIndexConfig(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				ix, err := c.TransformIndex()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, found := ix.Lookup("FQDN", "IPAddress"); !found {
+					t.Error("Lookup() did not match FQDN->IPAddress")
+					return
+				}
+				if _, err := c.CheckTransformations("FQDN", "IPAddress"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	// Loading the transformations again publishes a new index while the lookups run
+	for i := 0; i < 10; i++ {
+		if err := c.ReloadTransformations(); err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+}
+
+func BenchmarkCheckTransformations(b *testing.B) {
+	c := loadIndexConfig(b)
+	tos := []string{"IPAddress", "Netblock", "DomainRecord", "AlienVault", "TLSCertificate"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = c.CheckTransformations("FQDN", tos...)
+	}
+}
+
+func BenchmarkTransformIndexLookup(b *testing.B) {
+	c := loadIndexConfig(b)
+	tos := []string{"IPAddress", "Netblock", "DomainRecord", "AlienVault", "TLSCertificate"}
+	ix, err := c.TransformIndex()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, to := range tos {
+			_, _ = ix.Lookup("FQDN", to)
+		}
+	}
+}
+
+func BenchmarkTransformIndexLookupParallel(b *testing.B) {
+	c := loadIndexConfig(b)
+	ix, err := c.TransformIndex()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = ix.Lookup("FQDN", "IPAddress")
+		}
+	})
+}
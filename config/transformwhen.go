@@ -0,0 +1,281 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	oam "github.com/owasp-amass/open-asset-model"
+)
+
+// AssetMetadata holds the details about an asset that are not part of the asset itself.
+type AssetMetadata struct {
+	// The confidence in the incoming asset, from 0 to 100
+	Confidence int
+}
+
+// whenEnv holds the asset that the when clauses are evaluated against.
+type whenEnv struct {
+	c       *Config
+	asset   oam.Asset
+	meta    *AssetMetadata
+	checked bool
+	inScope bool
+}
+
+// whenExpr is a node of a compiled when clause.
+type whenExpr interface {
+	eval(env *whenEnv) bool
+}
+
+type whenAnd struct{ left, right whenExpr }
+
+type whenOr struct{ left, right whenExpr }
+
+type whenNot struct{ expr whenExpr }
+
+type whenInScope struct{}
+
+type whenConfidence struct {
+	op    string
+	value int
+}
+
+type whenNameMatches struct{ pattern string }
+
+func (e *whenAnd) eval(env *whenEnv) bool { return e.left.eval(env) && e.right.eval(env) }
+
+func (e *whenOr) eval(env *whenEnv) bool { return e.left.eval(env) || e.right.eval(env) }
+
+func (e *whenNot) eval(env *whenEnv) bool { return !e.expr.eval(env) }
+
+// eval checks the asset against the scope once, no matter how many times in_scope is used.
+func (e *whenInScope) eval(env *whenEnv) bool {
+	if !env.checked {
+		env.checked = true
+		env.inScope = env.c.InScope(env.asset).InScope
+	}
+	return env.inScope
+}
+
+func (e *whenConfidence) eval(env *whenEnv) bool {
+	var confidence int
+	if env.meta != nil {
+		confidence = env.meta.Confidence
+	}
+
+	switch e.op {
+	case ">=":
+		return confidence >= e.value
+	case ">":
+		return confidence > e.value
+	case "<=":
+		return confidence <= e.value
+	case "<":
+		return confidence < e.value
+	case "==":
+		return confidence == e.value
+	case "!=":
+		return confidence != e.value
+	}
+	return false
+}
+
+func (e *whenNameMatches) eval(env *whenEnv) bool {
+	matched, _ := path.Match(e.pattern, strings.ToLower(env.asset.Key()))
+	return matched
+}
+
+// parseWhen compiles a when clause. The clause combines the following conditions with and, or, not
+// and parentheses:
+//
+//	in_scope                    the asset is in scope
+//	confidence >= 70            the confidence in the asset, compared using >=, >, <=, <, == or !=
+//	name matches "*.owasp.org"  the key of the asset, such as the name or address, matches the glob pattern
+func parseWhen(clause string) (whenExpr, error) {
+	tokens, err := lexWhen(clause)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &whenParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != "" {
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+	return expr, nil
+}
+
+// whenParser is a recursive descent parser over the tokens of a when clause.
+type whenParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *whenParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *whenParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *whenParser) parseOr() (whenExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &whenOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *whenParser) parseAnd() (whenExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &whenAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *whenParser) parseUnary() (whenExpr, error) {
+	if strings.EqualFold(p.peek(), "not") {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &whenNot{expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *whenParser) parsePrimary() (whenExpr, error) {
+	tok := p.next()
+
+	switch strings.ToLower(tok) {
+	case "":
+		return nil, fmt.Errorf("unexpected end of the clause")
+	case "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return expr, nil
+	case "in_scope":
+		return &whenInScope{}, nil
+	case "confidence":
+		op := p.next()
+		switch op {
+		case ">=", ">", "<=", "<", "==", "!=":
+		default:
+			return nil, fmt.Errorf("confidence must be followed by a comparison operator, got %q", op)
+		}
+
+		num := p.next()
+		value, err := strconv.Atoi(num)
+		if err != nil || value < 0 || value > 100 {
+			return nil, fmt.Errorf("confidence must be compared with a number from 0 to 100, got %q", num)
+		}
+		return &whenConfidence{op: op, value: value}, nil
+	case "name":
+		if !strings.EqualFold(p.next(), "matches") {
+			return nil, fmt.Errorf("name must be followed by matches")
+		}
+
+		quoted := p.next()
+		pattern, err := strconv.Unquote(quoted)
+		if err != nil || !strings.HasPrefix(quoted, `"`) {
+			return nil, fmt.Errorf("name matches requires a quoted pattern, got %q", quoted)
+		}
+		pattern = strings.ToLower(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		return &whenNameMatches{pattern: pattern}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", tok)
+}
+
+// lexWhen splits the when clause into words, numbers, quoted strings, operators and parentheses.
+func lexWhen(clause string) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < len(clause); {
+		ch := rune(clause[i])
+
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(' || ch == ')':
+			tokens = append(tokens, string(ch))
+			i++
+		case strings.ContainsRune("<>=!", ch):
+			j := i + 1
+			if j < len(clause) && clause[j] == '=' {
+				j++
+			}
+			tokens = append(tokens, clause[i:j])
+			i = j
+		case ch == '"':
+			j := i + 1
+			for ; j < len(clause) && clause[j] != '"'; j++ {
+				if clause[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(clause) {
+				return nil, fmt.Errorf("unterminated string starting at position %d", i)
+			}
+			tokens = append(tokens, clause[i:j+1])
+			i = j + 1
+		case ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch):
+			j := i
+			for ; j < len(clause); j++ {
+				if c := rune(clause[j]); c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+					break
+				}
+			}
+			tokens = append(tokens, clause[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", ch, i)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,172 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/domain"
+	"github.com/owasp-amass/open-asset-model/network"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseWhen(t *testing.T) {
+	tests := []struct {
+		clause string
+		errMsg string
+	}{
+		{clause: "in_scope"},
+		{clause: "confidence >= 70"},
+		{clause: `name matches "*.owasp.org"`},
+		{clause: `IN_SCOPE and (confidence > 50 or not name matches "dev.*")`},
+		{clause: "not not in_scope"},
+		{clause: "", errMsg: "unexpected end"},
+		{clause: "in_scope and", errMsg: "unexpected end"},
+		{clause: "in_scope or or in_scope", errMsg: `unexpected "or"`},
+		{clause: "(in_scope", errMsg: "missing closing parenthesis"},
+		{clause: "in_scope)", errMsg: `unexpected ")"`},
+		{clause: "confidence = 70", errMsg: "comparison operator"},
+		{clause: "confidence >= high", errMsg: "number from 0 to 100"},
+		{clause: "confidence >= 101", errMsg: "number from 0 to 100"},
+		{clause: "name matches owasp", errMsg: "quoted pattern"},
+		{clause: "name matches *.owasp.org", errMsg: "unexpected character"},
+		{clause: `name matches "[owasp"`, errMsg: "invalid pattern"},
+		{clause: `name is "owasp.org"`, errMsg: "followed by matches"},
+		{clause: `name matches "owasp.org`, errMsg: "unterminated string"},
+		{clause: "in_scope && confidence > 5", errMsg: "unexpected character"},
+		{clause: "resolved", errMsg: `unexpected "resolved"`},
+	}
+	for _, tt := range tests {
+		_, err := parseWhen(tt.clause)
+		if tt.errMsg == "" {
+			if err != nil {
+				t.Errorf("parseWhen(%q) error = %v", tt.clause, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("parseWhen(%q) error = %v, want %q", tt.clause, err, tt.errMsg)
+		}
+	}
+}
+
+func TestCheckAssetTransformations(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - owasp.org
+  cidrs:
+    - 192.0.2.0/24
+transformations:
+  FQDN->IPAddress:
+    when: in_scope
+  FQDN->DomainRecord:
+    when: confidence >= 70
+  FQDN->TLSCertificate:
+    when: name matches "www.*" or name matches "*.dev.owasp.org"
+  FQDN->ALL:
+    exclude: [FQDN]
+  IPAddress->Netblock:
+    when: in_scope and confidence > 50
+`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadTransformSettings(c); err != nil {
+		t.Fatal(err)
+	}
+
+	tos := []string{"IPAddress", "DomainRecord", "TLSCertificate", "Netblock"}
+	tests := []struct {
+		name    string
+		asset   oam.Asset
+		meta    *AssetMetadata
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "every condition holds",
+			asset: &domain.FQDN{Name: "www.owasp.org"},
+			meta:  &AssetMetadata{Confidence: 90},
+			want:  []string{"domainrecord", "ipaddress", "netblock", "tlscertificate"},
+		},
+		{
+			name:  "out of scope with low confidence",
+			asset: &domain.FQDN{Name: "www.example.com"},
+			meta:  &AssetMetadata{Confidence: 40},
+			want:  []string{"netblock", "tlscertificate"},
+		},
+		{
+			name:  "no metadata",
+			asset: &domain.FQDN{Name: "api.dev.owasp.org"},
+			want:  []string{"ipaddress", "netblock", "tlscertificate"},
+		},
+		{
+			name:  "address in scope",
+			asset: &network.IPAddress{Address: netip.MustParseAddr("192.0.2.1"), Type: "IPv4"},
+			meta:  &AssetMetadata{Confidence: 60},
+			want:  []string{"netblock"},
+		},
+		{
+			name:    "address out of scope",
+			asset:   &network.IPAddress{Address: netip.MustParseAddr("198.51.100.1"), Type: "IPv4"},
+			meta:    &AssetMetadata{Confidence: 60},
+			wantErr: true,
+		},
+		{
+			name:    "nil asset",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := c.CheckAssetTransformations(tt.asset, tt.meta, tos...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckAssetTransformations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got []string
+			for _, to := range tos {
+				if m.IsMatch(to) {
+					got = append(got, strings.ToLower(to))
+				}
+			}
+			if !reflect.DeepEqual(sortedSet(got), tt.want) {
+				t.Errorf("CheckAssetTransformations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The conditions are not evaluated without the asset
+	if m, err := c.CheckTransformations("FQDN", tos...); err != nil || m.Len() != len(tos) {
+		t.Errorf("CheckTransformations() = %v, %v, want every target", m, err)
+	}
+}
+
+func TestLoadInvalidWhen(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+transformations:
+  FQDN->IPAddress:
+    when: confidence >> 70
+`), c); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.loadTransformSettings(c)
+	if err == nil || !strings.Contains(err.Error(), "invalid when clause for the transformation FQDN->IPAddress") {
+		t.Errorf("loadTransformSettings() error = %v, want the invalid when clause", err)
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/domain"
+	"github.com/owasp-amass/open-asset-model/network"
+	"github.com/owasp-amass/open-asset-model/url"
+)
+
+// The layout used for the dates and daily hours of the testing windows.
+const (
+	windowDateLayout = "2006-01-02"
+	windowHourLayout = "15:04"
+)
+
+// Window represents a period of time when active testing is permitted by the rules of engagement.
+// Windows without targets apply to the entire scope, while windows with targets only apply to the
+// domain names, addresses and CIDRs listed.
+type Window struct {
+	// The first date (YYYY-MM-DD) or time (RFC 3339) of the window
+	Start string `yaml:"start,omitempty" json:"start,omitempty"`
+
+	// The last date (YYYY-MM-DD), which is included in the window, or time (RFC 3339) of the window
+	End string `yaml:"end,omitempty" json:"end,omitempty"`
+
+	// The daily hours of the window, such as 09:00-17:00. Hours ending before they start cross midnight
+	Hours string `yaml:"hours,omitempty" json:"hours,omitempty"`
+
+	// The IANA time zone for the dates and hours, such as America/New_York (UTC by default)
+	TimeZone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+
+	// The domain names, addresses and CIDRs that the window applies to
+	Targets []string `yaml:"targets,omitempty" json:"targets,omitempty"`
+
+	loc      *time.Location
+	start    time.Time
+	end      time.Time
+	from     time.Duration
+	to       time.Duration
+	hasHours bool
+	domains  []string
+	prefixes []netip.Prefix
+}
+
+// populateWindows validates the testing windows and parses their dates, hours, time zones and targets.
+func (s *Scope) populateWindows() error {
+	for i, w := range s.Windows {
+		if w == nil {
+			continue
+		}
+		if err := w.parse(); err != nil {
+			return fmt.Errorf("invalid testing window %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (w *Window) parse() error {
+	loc := time.UTC
+	if tz := strings.TrimSpace(w.TimeZone); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid time zone: %v", tz, err)
+		}
+		loc = l
+	}
+	w.loc = loc
+
+	var err error
+	if w.Start != "" {
+		if w.start, err = parseWindowTime(w.Start, loc, false); err != nil {
+			return err
+		}
+	}
+	if w.End != "" {
+		if w.end, err = parseWindowTime(w.End, loc, true); err != nil {
+			return err
+		}
+	}
+	if !w.start.IsZero() && !w.end.IsZero() && !w.end.After(w.start) {
+		return fmt.Errorf("the end %s is not after the start %s", w.End, w.Start)
+	}
+
+	w.hasHours = false
+	if h := strings.TrimSpace(w.Hours); h != "" {
+		first, last, found := strings.Cut(h, "-")
+		if !found {
+			return fmt.Errorf("%s is not a valid range of hours", h)
+		}
+		if w.from, err = parseWindowHour(first); err != nil {
+			return err
+		}
+		if w.to, err = parseWindowHour(last); err != nil {
+			return err
+		}
+		if w.from == w.to {
+			return fmt.Errorf("%s is not a valid range of hours", h)
+		}
+		w.hasHours = true
+	}
+
+	w.domains, w.prefixes = nil, nil
+	for _, t := range w.Targets {
+		t = strings.TrimSpace(t)
+
+		if p, err := netip.ParsePrefix(t); err == nil {
+			w.prefixes = append(w.prefixes, p.Masked())
+		} else if a, err := netip.ParseAddr(t); err == nil {
+			w.prefixes = append(w.prefixes, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+		} else if n, err := NormalizeDomain(t); err == nil {
+			w.domains = append(w.domains, n)
+		} else {
+			return fmt.Errorf("%s is not a valid domain name, address or CIDR", t)
+		}
+	}
+	return nil
+}
+
+// parseWindowTime parses a date or RFC 3339 time. The end of a window provided as a date
+// includes the entire day.
+func parseWindowTime(s string, loc *time.Location, end bool) (time.Time, error) {
+	v := strings.TrimSpace(s)
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(windowDateLayout, v, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is not a valid date or RFC 3339 time", v)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func parseWindowHour(s string) (time.Duration, error) {
+	t, err := time.Parse(windowHourLayout, strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a valid time of day (HH:MM)", strings.TrimSpace(s))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains returns true if the time falls within the dates and daily hours of the window.
+func (w *Window) Contains(t time.Time) bool {
+	if !w.start.IsZero() && t.Before(w.start) {
+		return false
+	}
+	if !w.end.IsZero() && !t.Before(w.end) {
+		return false
+	}
+	if !w.hasHours {
+		return true
+	}
+
+	loc := w.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	lt := t.In(loc)
+	tod := time.Duration(lt.Hour())*time.Hour + time.Duration(lt.Minute())*time.Minute +
+		time.Duration(lt.Second())*time.Second + time.Duration(lt.Nanosecond())
+	if w.from < w.to {
+		return tod >= w.from && tod < w.to
+	}
+	// The hours cross midnight
+	return tod >= w.from || tod < w.to
+}
+
+// appliesTo returns true if the window lists a target matching the name or network.
+func (w *Window) appliesTo(name string, prefix netip.Prefix) bool {
+	if name != "" {
+		for _, d := range w.domains {
+			if hasPathSuffix(name, d) {
+				return true
+			}
+		}
+	}
+	if prefix.IsValid() {
+		for _, p := range w.prefixes {
+			if p.Overlaps(prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ActiveAllowedAt returns true if active testing of the asset, such as a zone transfer, is permitted at
+// the time provided. Windows listing a target that matches the asset take precedence over the windows
+// without targets. The asset is permitted at any time when no window applies to it.
+func (c *Config) ActiveAllowedAt(asset oam.Asset, t time.Time) bool {
+	c.RLock()
+	var windows []*Window
+	if c.Scope != nil {
+		windows = c.Scope.Windows
+	}
+	c.RUnlock()
+
+	name, prefix := windowTarget(asset)
+
+	var specific, general []*Window
+	for _, w := range windows {
+		if w == nil {
+			continue
+		}
+		if len(w.Targets) == 0 {
+			general = append(general, w)
+		} else if w.appliesTo(name, prefix) {
+			specific = append(specific, w)
+		}
+	}
+
+	applicable := general
+	if len(specific) > 0 {
+		applicable = specific
+	}
+	if len(applicable) == 0 {
+		return true
+	}
+
+	for _, w := range applicable {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// windowTarget returns the DNS name or network of the asset used to select the testing windows.
+func windowTarget(asset oam.Asset) (string, netip.Prefix) {
+	var name string
+	var addr netip.Addr
+
+	switch v := asset.(type) {
+	case *domain.FQDN:
+		name = v.Name
+	case domain.FQDN:
+		name = v.Name
+	case *domain.NetworkEndpoint:
+		name = v.Name
+	case domain.NetworkEndpoint:
+		name = v.Name
+	case *url.URL:
+		name = v.Host
+	case url.URL:
+		name = v.Host
+	case *network.IPAddress:
+		addr = v.Address
+	case network.IPAddress:
+		addr = v.Address
+	case *network.SocketAddress:
+		addr = v.Address.Addr()
+	case network.SocketAddress:
+		addr = v.Address.Addr()
+	case *network.Netblock:
+		return "", v.CIDR.Masked()
+	case network.Netblock:
+		return "", v.CIDR.Masked()
+	}
+
+	// DNS names can also be IP addresses, as found in URLs
+	if a, err := netip.ParseAddr(name); err == nil {
+		name, addr = "", a
+	}
+	if addr.IsValid() {
+		addr = addr.Unmap()
+		return "", netip.PrefixFrom(addr, addr.BitLen())
+	}
+	if name != "" {
+		name = normalizeQuery(strings.TrimPrefix(name, "*."))
+	}
+	return name, netip.Prefix{}
+}
@@ -0,0 +1,159 @@
+// Copyright © by Jeff Foley 2017-2024. All rights reserved.
+// Use of this source code is governed by Apache 2 LICENSE that can be found in the LICENSE file.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	oam "github.com/owasp-amass/open-asset-model"
+	"github.com/owasp-amass/open-asset-model/domain"
+	"github.com/owasp-amass/open-asset-model/network"
+	"gopkg.in/yaml.v3"
+)
+
+func TestActiveAllowedAt(t *testing.T) {
+	c := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+scope:
+  domains:
+    - owasp.org
+    - example.com
+  cidrs:
+    - 192.0.2.0/24
+  windows:
+    - start: 2024-06-01
+      end: 2024-06-30
+      hours: "09:00-17:00"
+      timezone: UTC
+    - hours: "22:00-02:00"
+      targets:
+        - example.com
+        - 192.0.2.128/25`), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.loadSeedandScopeSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		asset oam.Asset
+		when  string
+		want  bool
+	}{
+		{
+			name:  "within the dates and hours",
+			asset: &domain.FQDN{Name: "www.owasp.org"},
+			when:  "2024-06-10T10:00:00Z",
+			want:  true,
+		},
+		{
+			name:  "last day of the window",
+			asset: &domain.FQDN{Name: "www.owasp.org"},
+			when:  "2024-06-30T16:59:00Z",
+			want:  true,
+		},
+		{
+			name:  "outside the hours",
+			asset: &domain.FQDN{Name: "www.owasp.org"},
+			when:  "2024-06-10T17:00:00Z",
+		},
+		{
+			name:  "after the end date",
+			asset: &domain.FQDN{Name: "www.owasp.org"},
+			when:  "2024-07-01T10:00:00Z",
+		},
+		{
+			name:  "hours provided in another time zone",
+			asset: &network.IPAddress{Address: netip.MustParseAddr("192.0.2.1"), Type: "IPv4"},
+			when:  "2024-06-10T12:30:00+02:00",
+			want:  true,
+		},
+		{
+			name:  "target window before midnight",
+			asset: &domain.FQDN{Name: "www.example.com"},
+			when:  "2024-06-10T23:00:00Z",
+			want:  true,
+		},
+		{
+			name:  "target window after midnight",
+			asset: &domain.FQDN{Name: "www.example.com"},
+			when:  "2024-06-11T01:30:00Z",
+			want:  true,
+		},
+		{
+			name:  "target window takes precedence",
+			asset: &domain.FQDN{Name: "www.example.com"},
+			when:  "2024-06-10T10:00:00Z",
+		},
+		{
+			name:  "address within a target cidr",
+			asset: &network.IPAddress{Address: netip.MustParseAddr("192.0.2.200"), Type: "IPv4"},
+			when:  "2024-06-10T10:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			when, err := time.Parse(time.RFC3339, tt.when)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.ActiveAllowedAt(tt.asset, when); got != tt.want {
+				t.Errorf("ActiveAllowedAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActiveAllowedAtWithoutWindows(t *testing.T) {
+	c := NewConfig()
+	c.Scope.Domains = []string{"owasp.org"}
+
+	if !c.ActiveAllowedAt(&domain.FQDN{Name: "www.owasp.org"}, time.Now()) {
+		t.Errorf("ActiveAllowedAt() = false, want true when no windows are configured")
+	}
+}
+
+func TestWindowsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		window *Window
+	}{
+		{
+			name:   "invalid date",
+			window: &Window{Start: "06/01/2024"},
+		},
+		{
+			name:   "end before start",
+			window: &Window{Start: "2024-06-30", End: "2024-06-01"},
+		},
+		{
+			name:   "invalid hours",
+			window: &Window{Hours: "9am-5pm"},
+		},
+		{
+			name:   "empty hours",
+			window: &Window{Hours: "09:00-09:00"},
+		},
+		{
+			name:   "invalid time zone",
+			window: &Window{TimeZone: "Mars/Olympus_Mons"},
+		},
+		{
+			name:   "invalid target",
+			window: &Window{Targets: []string{"example..com"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scope{Windows: []*Window{tt.window}}
+			if err := s.populateWindows(); err == nil {
+				t.Errorf("populateWindows() expected an error")
+			}
+		})
+	}
+}